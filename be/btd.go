@@ -4,13 +4,13 @@ import (
 	"btd/curves"
 	"btd/elgamal"
 	"btd/prf"
+	"btd/transcript"
+	"bytes"
+	"errors"
 	"fmt"
 	"go.dedis.ch/kyber/v4"
-	"go.dedis.ch/kyber/v4/pairing"
 	"go.dedis.ch/kyber/v4/share"
-	"hash"
 	"math"
-	"strconv"
 )
 
 type Proof struct {
@@ -22,59 +22,162 @@ type Proof struct {
 }
 
 type CT struct {
+	suite curves.Suite
 	i     int
+	ad    []byte
 	gamma kyber.Point
 	kp    kyber.Point
 	c     elgamal.CT
 	pi    Proof
-	m     kyber.Point
 }
 
+// Index returns the index of the CRS the ciphertext was encrypted for.
+func (ct CT) Index() int {
+	return ct.i
+}
+
+// AD returns the associated data the ciphertext is bound to.
+func (ct CT) AD() []byte {
+	return ct.ad
+}
+
+// ErrADMismatch is returned by CheckAD for a ciphertext bound to different associated data.
+var ErrADMismatch = errors.New("ciphertext bound to different associated data")
+
+// CheckAD checks that all ciphertexts in cts are bound to the associated data ad, e.g., the label of the batch
+// window they are decrypted in. The proof of a ciphertext binds it to its associated data, so together with
+// VerifyCT, this rejects ciphertexts replayed from another batch.
+func CheckAD(cts []CT, ad []byte) error {
+	for _, ct := range cts {
+		if !bytes.Equal(ct.ad, ad) {
+			return fmt.Errorf("ciphertext on index %d: %w", ct.i, ErrADMismatch)
+		}
+	}
+	return nil
+}
+
+// BTD is an instance of the batched threshold decryption scheme for a fixed CRS.
+//
+// The CRS is immutable. The key is set once by KeyGen, KeyGenDKG or SetKey, which must not run concurrently with
+// other methods. After that, a BTD is read-only and all methods are safe for concurrent use: every call draws fresh
+// randomness from the suite and uses its own hash and pairing state, so a gateway may encrypt and a combiner may
+// combine batches from many goroutines with one shared instance.
 type BTD struct {
-	suite pairing.Suite
-	prf   *prf.PRF
+	suite curves.Suite
+	prf   *prf.CRS
 	eg    *elgamal.ElGamal
 	B     int
-	H     *Hasher
 	T     int
 	N     int
+
+	crsDigest []byte
 }
 
-func (b *BTD) VerifyCT(ct CT) bool {
-	h, err := b.SHash(b.eg.PK, ct, ct.pi.Ap, ct.pi.Bp, ct.pi.yp)
+// ProofError reports that the proof of the ciphertext on index Index is invalid. Equation names the verification
+// equation that failed: "A" and "B" for the ElGamal ciphertext and "y" for the punctured key. If the proof could not
+// be checked at all, Equation is empty and Err holds the cause.
+type ProofError struct {
+	Index    int
+	Equation string
+	Err      error
+}
+
+func (e *ProofError) Error() string {
+	if e.Equation == "" {
+		return fmt.Sprintf("proof on index %d cannot be verified: %v", e.Index, e.Err)
+	}
+	return fmt.Sprintf("proof on index %d failed: equation %s does not hold", e.Index, e.Equation)
+}
+
+func (e *ProofError) Unwrap() error {
+	return e.Err
+}
+
+// VerifyCT verifies the proof of ct and returns a *ProofError if it is invalid.
+func (b *BTD) VerifyCT(ct CT) error {
+	h, err := b.challenge(ct)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	al := b.suite.G1().Point().Mul(ct.pi.uHat, nil)
 	ar := b.suite.G1().Point().Add(ct.pi.Ap, b.suite.G1().Point().Mul(h, ct.c.A))
 	if !al.Equal(ar) {
-		panic("proof failed")
+		return &ProofError{Index: ct.i, Equation: "A"}
 	}
 	bl := b.suite.G1().Point().Add(b.suite.G1().Point().Mul(ct.pi.uHat, b.eg.PK), b.suite.G1().Point().Mul(ct.pi.kHat, nil))
 	br := b.suite.G1().Point().Add(ct.pi.Bp, b.suite.G1().Point().Mul(h, ct.c.B))
 	if !bl.Equal(br) {
-		panic("proof failed")
+		return &ProofError{Index: ct.i, Equation: "B"}
 	}
 	yl := b.suite.G1().Point().Mul(ct.pi.kHat, b.prf.G1xi[ct.i])
 	yr := b.suite.G1().Point().Add(ct.pi.yp, b.suite.G1().Point().Mul(h, ct.kp))
 	if !yl.Equal(yr) {
-		panic("proof failed")
+		return &ProofError{Index: ct.i, Equation: "y"}
+	}
+	return nil
+}
+
+// challenge checks that the proof of ct can be verified and returns its challenge.
+func (b *BTD) challenge(ct CT) (kyber.Scalar, error) {
+	if ct.i < 0 || ct.i >= b.B {
+		return nil, &ProofError{Index: ct.i, Err: fmt.Errorf("index out of range [0, %d-1]", b.B)}
+	}
+	if ct.gamma == nil || ct.kp == nil || ct.c.A == nil || ct.c.B == nil || ct.pi.Ap == nil || ct.pi.Bp == nil ||
+		ct.pi.yp == nil || ct.pi.kHat == nil || ct.pi.uHat == nil {
+		return nil, &ProofError{Index: ct.i, Err: errors.New("incomplete ciphertext")}
+	}
+	h, err := b.SHash(b.eg.PK, ct, ct.pi.Ap, ct.pi.Bp, ct.pi.yp)
+	if err != nil {
+		return nil, &ProofError{Index: ct.i, Err: err}
+	}
+	return h, nil
+}
+
+// FilterCTs returns the ciphertexts of cts with a valid proof, in order, and the errors of the rejected ones. Of
+// several valid ciphertexts on the same index, only the first is kept and the others are rejected with
+// ErrDuplicateIndex. As the filter is deterministic, all committee members that filter the same batch agree on the
+// ciphertexts to decrypt.
+func (b *BTD) FilterCTs(cts []CT) ([]CT, []error) {
+	valid := make([]CT, 0, len(cts))
+	seen := make(map[int]struct{}, len(cts))
+	var errs []error
+	for _, ct := range cts {
+		if err := b.VerifyCT(ct); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, ok := seen[ct.i]; ok {
+			errs = append(errs, fmt.Errorf("ciphertext on index %d: %w", ct.i, ErrDuplicateIndex))
+			continue
+		}
+		seen[ct.i] = struct{}{}
+		valid = append(valid, ct)
 	}
-	return true
+	return valid, errs
 }
 
 func NewBTD(suite curves.Suite, B int) *BTD {
-	prf := prf.PRFSetup(suite, B, true)
-	eg := elgamal.NewElGamal(suite.G1(), suite.RandomStream())
+	return NewBTDWithCRS(suite, prf.PRFSetup(suite, B, true))
+}
+
+// NewBTDWithCRS creates a BTD instance from an existing CRS, e.g., one loaded with prf.LoadCRS.
+func NewBTDWithCRS(suite curves.Suite, crs *prf.CRS) *BTD {
+	eg := elgamal.NewElGamal(suite.G1(), suite)
 	return &BTD{
 		suite: suite,
-		prf:   prf,
+		prf:   crs,
 		eg:    eg,
-		B:     B,
-		H:     &Hasher{hash: suite.Hash()},
+		B:     crs.B,
+
+		crsDigest: crs.Digest(),
 	}
 }
 
+// CRS returns the CRS of the batched PRF.
+func (b *BTD) CRS() *prf.CRS {
+	return b.prf
+}
+
 func (b *BTD) KeyGen(n, t int) ([]*share.PriShare, kyber.Point) {
 	// Key Generation is just ElGamal KeyGen
 	sk, pk := b.eg.KeyGen(n, t)
@@ -82,7 +185,27 @@ func (b *BTD) KeyGen(n, t int) ([]*share.PriShare, kyber.Point) {
 	return sk, pk
 }
 
-func (b *BTD) Enc(pk kyber.Point, i int, m kyber.Point) (CT, error) {
+// KeyGenDKG generates the key with a DKG between n in-process committee members instead of a trusted dealer. The
+// i-th returned key share belongs to committee member i.
+func (b *BTD) KeyGenDKG(n, t int) ([]*elgamal.DistKeyShare, kyber.Point, error) {
+	keys, err := elgamal.RunDKG(b.suite.G1(), b.suite.RandomStream(), n, t)
+	if err != nil {
+		return nil, nil, err
+	}
+	b.SetKey(keys[0].Commits, n)
+	return keys, b.eg.PK, nil
+}
+
+// SetKey sets the public key from the commitments to the committee's sharing of the secret key, e.g., the
+// commitments output by a DKG run between the committee members.
+func (b *BTD) SetKey(commits *share.PubPoly, n int) {
+	b.eg.SetKey(commits, n)
+	b.T, b.N = commits.Threshold(), n
+}
+
+// Enc encrypts m under pk on index i. The ciphertext is bound to the associated data ad, such as the epoch, chain id
+// or sender, which is not encrypted but covered by the proof.
+func (b *BTD) Enc(pk kyber.Point, i int, m kyber.Point, ad []byte) (CT, error) {
 	// Generate a PRF key
 	k := b.prf.KeyGen()
 	// Puncture it in the i-th index
@@ -109,11 +232,12 @@ func (b *BTD) Enc(pk kyber.Point, i int, m kyber.Point) (CT, error) {
 	Bp := b.suite.G1().Point().Add(b.suite.G1().Point().Mul(uN, b.eg.PK), b.suite.G1().Point().Mul(kN, nil))
 	yp := b.suite.G1().Point().Mul(kN, b.prf.G1xi[i])
 	ct := CT{
+		suite: b.suite,
 		i:     i,
+		ad:    bytes.Clone(ad),
 		gamma: gamma,
 		kp:    kp,
 		c:     egct,
-		m:     m,
 	}
 	h, err := b.SHash(pk, ct, Ap, Bp, yp)
 	if err != nil {
@@ -131,7 +255,10 @@ func (b *BTD) Enc(pk kyber.Point, i int, m kyber.Point) (CT, error) {
 	return ct, nil
 }
 
-func (b *BTD) BatchDec(cts []CT, i int, verify bool) (*share.PubShare, error) {
+// BatchDec computes the decryption share of the batch cts with the secret share sk. The batch may hold ciphertexts
+// on any subset of the indices, but no index twice, see EmptySlots. The share carries a proof that it was computed
+// with sk, which the combiner checks with VerifyShare.
+func (b *BTD) BatchDec(cts []CT, sk *share.PriShare, verify bool) (*elgamal.DecShare, error) {
 	if len(cts) > b.B {
 		return nil, fmt.Errorf("too many ciphertexts for the given crs")
 	}
@@ -140,83 +267,129 @@ func (b *BTD) BatchDec(cts []CT, i int, verify bool) (*share.PubShare, error) {
 	if err != nil {
 		return nil, err
 	}
-	return b.eg.PDec(C, i), nil
+	return b.eg.PDec(C, sk)
 }
 
-func (b *BTD) BatchCombine(cts []CT, d []*share.PubShare, verify bool) (int, error) {
-	// Keep count of the number of pairings for testing purposes.
-	count := 0
-	ActualB := len(cts)
-	if ActualB > b.B {
-		return count, fmt.Errorf("too many ciphertexts for the given crs")
+// VerifyShare checks that the decryption share d of the batch cts was computed with the secret share of its index.
+func (b *BTD) VerifyShare(cts []CT, d *elgamal.DecShare) error {
+	C, err := b.SumEGCt(cts, false)
+	if err != nil {
+		return err
+	}
+	return b.eg.VerifyShare(C, d)
+}
+
+// BatchCombine combines the decryption shares d of the batch cts and recovers the messages of all ciphertexts
+// in the batch. The i-th returned message is the plaintext of cts[i]. As for BatchDec, the batch may leave slots
+// empty but must not use an index twice. If verify is set, both the ciphertexts and the decryption shares are
+// verified.
+func (b *BTD) BatchCombine(cts []CT, d []*elgamal.DecShare, verify bool) ([]kyber.Point, error) {
+	K, err := b.combineKey(cts, d, verify)
+	if err != nil {
+		return nil, err
+	}
+	return b.unpad(cts, K)
+}
+
+// combineKey combines the decryption shares d of the batch cts to obtain K = g_1^{sum(k_i)}.
+func (b *BTD) combineKey(cts []CT, d []*elgamal.DecShare, verify bool) (kyber.Point, error) {
+	if len(cts) > b.B {
+		return nil, fmt.Errorf("too many ciphertexts for the given crs")
 	}
 	C, err := b.SumEGCt(cts, verify)
 	if err != nil {
-		return count, err
+		return nil, err
+	}
+	if verify {
+		for _, di := range d {
+			if err := b.eg.VerifyShare(C, di); err != nil {
+				return nil, err
+			}
+		}
 	}
 	// Combine all ElGamal decryption shares to obtain K = g_1^{sum(k_i)}
-	K, err := b.eg.Combine(C, d)
+	return b.eg.Combine(C, d)
+}
+
+// BatchCombineRobust is BatchCombine for decryption shares that may be faulty. The shares are combined with
+// elgamal.CombineRobust, which interpolates t valid shares only. The returned report names the faulty shares and is
+// also returned if too many shares are faulty to decrypt. If verify is set, the ciphertexts are verified.
+func (b *BTD) BatchCombineRobust(
+	cts []CT, d []*elgamal.DecShare, verify bool,
+) ([]kyber.Point, *elgamal.CombineReport, error) {
+	if len(cts) > b.B {
+		return nil, nil, fmt.Errorf("too many ciphertexts for the given crs")
+	}
+	C, err := b.SumEGCt(cts, verify)
+	if err != nil {
+		return nil, nil, err
+	}
+	K, report, err := b.eg.CombineRobust(C, d)
 	if err != nil {
-		return count, err
+		return nil, report, err
 	}
+	ms, err := b.unpad(cts, K)
+	return ms, report, err
+}
+
+// unpad recovers the messages of the batch cts from K = g_1^{sum(k_i)}.
+func (b *BTD) unpad(cts []CT, K kyber.Point) ([]kyber.Point, error) {
+	ms := make([]kyber.Point, len(cts))
 	// decrypt each ciphertext in the batch (1 iteration = 1 ciphertext)
-	for _, ct := range cts {
-		count++
-		// compute PRF(sum(k_i), i ) through exponential evaluation with K
-		prfKi, err := b.prf.ExpEval(K, ct.i)
-		if err != nil {
-			return count, err
-		}
-		sum := b.suite.GT().Point().Null()
-		// iterate over all other ciphertexts in the batch
-		for j := 0; j < ActualB; j++ {
-			ji := cts[j].i
-			if ji == ct.i {
-				continue
-			}
-			count++
-			// compute PRF(k_j, i) through punctured evaluation with kp_j
-			peval, err := b.prf.PEval(cts[j].kp, ji, ct.i)
-			if err != nil {
-				str := fmt.Sprintf("PEval on puncutured index %d on index %d failed: ", cts[j].i, ct.i)
-				for _, c := range cts {
-					str += fmt.Sprintf("%d ", c.i)
-				}
-				panic(str)
-				return count, err
-			}
-			// compute the sum of all the punctured evaluations
-			sum = b.suite.GT().Point().Add(sum, peval)
+	for idx := range cts {
+		var err error
+		if ms[idx], err = b.unpadOne(cts, K, idx); err != nil {
+			return nil, err
 		}
-		// compute the message by undoing the padding m = (gamma + sum(PRf(k_j, i))) - PRF(sum(k_i), i)
-		m := b.suite.GT().Point().Sub(b.suite.GT().Point().Add(ct.gamma, sum), prfKi)
-		if !m.Equal(ct.m) {
-			return count, fmt.Errorf("decryption failed on index %d", ct.i)
+	}
+	return ms, nil
+}
+
+// unpadOne recovers the message of cts[idx] from K = g_1^{sum(k_i)}.
+func (b *BTD) unpadOne(cts []CT, K kyber.Point, idx int) (kyber.Point, error) {
+	ct := cts[idx]
+	// compute PRF(sum(k_i), i ) through exponential evaluation with K
+	prfKi, err := b.prf.ExpEval(K, ct.i)
+	if err != nil {
+		return nil, err
+	}
+	// collect the punctured keys of all other ciphertexts in the batch
+	kps := make([]kyber.Point, 0, len(cts)-1)
+	pis := make([]int, 0, len(cts)-1)
+	for j := 0; j < len(cts); j++ {
+		if cts[j].i == ct.i {
+			continue
 		}
+		kps = append(kps, cts[j].kp)
+		pis = append(pis, cts[j].i)
 	}
-	return count, nil
+	// compute sum(PRF(k_j, i)) through punctured evaluation with all kp_j
+	sum, err := b.prf.PEvalMany(kps, pis, ct.i)
+	if err != nil {
+		return nil, fmt.Errorf("PEval on index %d failed: %w", ct.i, err)
+	}
+	// compute the message by undoing the padding m = (gamma + sum(PRf(k_j, i))) - PRF(sum(k_i), i)
+	return b.suite.GT().Point().Sub(b.suite.GT().Point().Add(ct.gamma, sum), prfKi), nil
 }
 
 // Outdated optimization, not used for final results!
-func (b *BTD) BatchDecOpt(cts []CT, i int, verify bool) ([]*share.PubShare, error) {
+func (b *BTD) BatchDecOpt(cts []CT, sk *share.PriShare, verify bool) ([]*elgamal.DecShare, error) {
 	L := len(cts)
 	if L > b.B {
 		return nil, fmt.Errorf("too many ciphertexts for the given crs")
 	}
 	if verify { //verify all the zk proofs in the batch
-		for _, ct := range cts {
-			if !b.VerifyCT(ct) {
-				return nil, fmt.Errorf("proof failed for index %d", ct.i)
-			}
+		if err := b.VerifyBatch(cts); err != nil {
+			return nil, err
 		}
 	}
 	lgL := int(math.Ceil(math.Log2(float64(L))))
-	Ks := make([]*share.PubShare, lgL)
+	Ks := make([]*elgamal.DecShare, lgL)
 	var err error
 	for l := 0; l < lgL; l++ {
 		x := math.Pow(2, float64(l))
 		start := int(math.Floor(float64(L) * (x - 1.0) / x))
-		Ks[l], err = b.BatchDec(cts[start:], i, false)
+		Ks[l], err = b.BatchDec(cts[start:], sk, false)
 		if err != nil {
 			return nil, err
 		}
@@ -225,34 +398,33 @@ func (b *BTD) BatchDecOpt(cts []CT, i int, verify bool) ([]*share.PubShare, erro
 }
 
 // Outdated optimization, not used for final result!
-func (b *BTD) BatchCombineOpt(cts []CT, ShareKs [][]*share.PubShare, verify bool) (int, error) {
-	count := 0
+func (b *BTD) BatchCombineOpt(cts []CT, ShareKs [][]*elgamal.DecShare, verify bool) ([]kyber.Point, error) {
 	L := len(cts)
 	if L > b.B {
-		return count, fmt.Errorf("too many ciphertexts for the given crs")
+		return nil, fmt.Errorf("too many ciphertexts for the given crs")
 	}
 	lgL := int(math.Ceil(math.Log2(float64(L))))
 	Ks := make([]kyber.Point, lgL)
 	for l := 0; l < lgL; l++ {
 		x := math.Pow(2, float64(l))
 		start := int(math.Floor(float64(L) * (x - 1.0) / x))
-		shares := make([]*share.PubShare, len(ShareKs))
+		shares := make([]*elgamal.DecShare, len(ShareKs))
 		for j, s := range ShareKs {
 			shares[j] = s[l]
 		}
 		C, err := b.SumEGCt(cts[start:], verify)
 		if err != nil {
-			return count, err
+			return nil, err
 		}
 		Ks[l], err = b.eg.Combine(C, shares)
 		if err != nil {
-			return count, err
+			return nil, err
 		}
 	}
+	ms := make([]kyber.Point, L)
 	KsIdx := 1
 	oldStart := 0
 	for idx, ct := range cts {
-		count++
 		sum := b.suite.GT().Point().Null()
 		x := math.Pow(2, float64(KsIdx))
 		nextStart := int(math.Floor(float64(L) * (x - 1.0) / x))
@@ -266,14 +438,13 @@ func (b *BTD) BatchCombineOpt(cts []CT, ShareKs [][]*share.PubShare, verify bool
 		}
 		prfKi, err := b.prf.ExpEval(Ks[KsIdx-1], ct.i)
 		if err != nil {
-			return count, err
+			return nil, err
 		}
 		if idx > oldStart {
 			for j := oldStart; j < idx; j++ {
-				count++
 				peval, err := b.prf.PEval(cts[j].kp, cts[j].i, ct.i)
 				if err != nil {
-					return count, err
+					return nil, err
 				}
 				sum = b.suite.GT().Point().Add(sum, peval)
 			}
@@ -282,93 +453,61 @@ func (b *BTD) BatchCombineOpt(cts []CT, ShareKs [][]*share.PubShare, verify bool
 			var eval kyber.Point
 
 			if KsIdx < len(Ks) && j == nextStart {
-				count++
 				eval, err = b.prf.ExpEval(Ks[KsIdx], ct.i)
 				if err != nil {
-					return count, err
+					return nil, err
 				}
 				sum = b.suite.GT().Point().Add(sum, eval)
 				break
 			}
-			count++
 			eval, err = b.prf.PEval(cts[j].kp, cts[j].i, ct.i)
 			if err != nil {
-				return count, err
+				return nil, err
 			}
 			sum = b.suite.GT().Point().Add(sum, eval)
 
 		}
-		m := b.suite.GT().Point().Sub(b.suite.GT().Point().Add(ct.gamma, sum), prfKi)
-		if !m.Equal(ct.m) {
-			return count, fmt.Errorf("decryption failed on index %d", ct.i)
-		}
+		ms[idx] = b.suite.GT().Point().Sub(b.suite.GT().Point().Add(ct.gamma, sum), prfKi)
 	}
-	return count, nil
+	return ms, nil
 }
 
+// SumEGCt sums the ElGamal ciphertexts of the batch cts. It fails with ErrDuplicateIndex if two ciphertexts share an
+// index. If verify is set, the proofs are checked with VerifyBatch and it fails with the joined *ProofError of every
+// invalid ciphertext; use FilterCTs to drop invalid and duplicate ciphertexts instead.
 func (b *BTD) SumEGCt(cts []CT, verify bool) (elgamal.CT, error) {
 	// Sum up all ElGamal ciphertext within the BTD ciphertexts.
-	// Also verify the proof, if verify is set to true.
 	sum := b.eg.NullEGct()
-	for _, ct := range cts {
-		if verify {
-			if !b.VerifyCT(ct) {
-				return sum, fmt.Errorf("proof failed for index %d", ct.i)
-			}
+	if err := checkIndices(cts); err != nil {
+		return sum, err
+	}
+	if verify {
+		if err := b.VerifyBatch(cts); err != nil {
+			return sum, err
 		}
+	}
+	for _, ct := range cts {
 		sum = b.eg.AddCT(sum, ct.c)
 	}
 	return sum, nil
 }
 
-type Hasher struct {
-	hash hash.Hash
-}
-
-func (h *Hasher) Hash(a []byte) []byte {
-	h.hash.Reset()
-	h.hash.Write(a)
-	return h.hash.Sum(nil)
-}
-
-func (h *Hasher) Size() int {
-	return h.hash.Size()
-}
-
+// SHash computes the Fiat-Shamir challenge of the proof of ciphertext c with the commitments Ap, Bp and yp. The
+// transcript commits to the CRS, the public key, the index i with its CRS element G1xi, the associated data, the
+// statement (gamma, kp, A, B) and the commitments.
 func (b *BTD) SHash(pk kyber.Point, c CT, Ap, Bp, yp kyber.Point) (kyber.Scalar, error) {
-	// Hash to compute the challenge for Schnorr ZK proof.
-	h := b.suite.Hash()
-	h.Reset()
-	if _, err := h.Write([]byte("pp")); err != nil { // Replace with actual setup
-		return nil, err
-
-	}
-	if _, err := pk.MarshalTo(h); err != nil {
-		return nil, err
-	}
-	if _, err := Ap.MarshalTo(h); err != nil {
-		return nil, err
-	}
-	if _, err := Bp.MarshalTo(h); err != nil {
-		return nil, err
-	}
-	if _, err := yp.MarshalTo(h); err != nil {
-		return nil, err
-	}
-	if _, err := h.Write([]byte(strconv.Itoa(c.i))); err != nil {
-		return nil, err
-	}
-	if _, err := c.gamma.MarshalTo(h); err != nil {
-		return nil, err
-	}
-	if _, err := c.kp.MarshalTo(h); err != nil {
-		return nil, err
-	}
-	if _, err := c.c.A.MarshalTo(h); err != nil {
-		return nil, err
-	}
-	if _, err := c.c.B.MarshalTo(h); err != nil {
-		return nil, err
-	}
-	return b.suite.G1().Scalar().SetBytes(h.Sum(nil)), nil
+	t := transcript.New("btd-ciphertext-proof")
+	t.Append("crs", b.crsDigest)
+	t.AppendMarshaler("pk", pk)
+	t.AppendUint32("index", uint32(c.i))
+	t.AppendMarshaler("G1xi", b.prf.G1xi[c.i])
+	t.Append("ad", c.ad)
+	t.AppendMarshaler("gamma", c.gamma)
+	t.AppendMarshaler("kp", c.kp)
+	t.AppendMarshaler("A", c.c.A)
+	t.AppendMarshaler("B", c.c.B)
+	t.AppendMarshaler("Ap", Ap)
+	t.AppendMarshaler("Bp", Bp)
+	t.AppendMarshaler("yp", yp)
+	return t.Challenge("h", b.suite.G1())
 }
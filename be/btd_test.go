@@ -0,0 +1,572 @@
+package be_test
+
+import (
+	"btd/be"
+	"btd/curves"
+	"btd/elgamal"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/pairing/bls12381/kilic"
+	"go.dedis.ch/kyber/v4/share"
+	"sync"
+	"testing"
+)
+
+func setup(B, n, th int) (curves.Suite, *be.BTD, []*share.PriShare, kyber.Point) {
+	suite := curves.NewSuite(kilic.NewBLS12381Suite())
+	btd, sks, pk := setupSuite(suite, B, n, th)
+	return suite, btd, sks, pk
+}
+
+func setupSuite(suite curves.Suite, B, n, th int) (*be.BTD, []*share.PriShare, kyber.Point) {
+	btd := be.NewBTD(suite, B)
+	sks, pk := btd.KeyGen(n, th)
+	return btd, sks, pk
+}
+
+// allSuites returns every registered suite with both assignments of the groups to the roles of the scheme.
+func allSuites() []curves.Suite {
+	var suites []curves.Suite
+	for _, suite := range curves.All() {
+		suites = append(suites, suite, curves.WithRoles(suite, curves.CRSInG1))
+	}
+	return suites
+}
+
+func encryptBatch(t *testing.T, suite curves.Suite, btd *be.BTD, pk kyber.Point) ([]be.CT, []kyber.Point) {
+	cts := make([]be.CT, btd.B)
+	ms := make([]kyber.Point, btd.B)
+	for i := 0; i < btd.B; i++ {
+		ms[i] = suite.PickGT()
+		ct, err := btd.Enc(pk, i, ms[i], nil)
+		require.NoError(t, err)
+		cts[i] = ct
+	}
+	return cts, ms
+}
+
+func TestBatchCombine(t *testing.T) {
+	for _, suite := range allSuites() {
+		t.Run(suite.Name(), func(t *testing.T) {
+			testBatchCombine(t, suite)
+		})
+	}
+}
+
+func testBatchCombine(t *testing.T, suite curves.Suite) {
+	btd, sks, pk := setupSuite(suite, 8, 10, 5)
+	cts, ms := encryptBatch(t, suite, btd, pk)
+	d := make([]*elgamal.DecShare, btd.T)
+	for i := 0; i < btd.T; i++ {
+		var err error
+		d[i], err = btd.BatchDec(cts, sks[i], true)
+		require.NoError(t, err)
+	}
+	res, err := btd.BatchCombine(cts, d, true)
+	require.NoError(t, err)
+	require.Len(t, res, len(ms))
+	for i := range ms {
+		require.True(t, ms[i].Equal(res[i]), "wrong message on index %d", i)
+	}
+
+	// A share for a different batch is rejected.
+	require.NoError(t, btd.VerifyShare(cts, d[0]))
+	require.Error(t, btd.VerifyShare(cts[1:], d[0]))
+	d[0], d[1] = d[1], &elgamal.DecShare{PubShare: d[0].PubShare, Proof: d[1].Proof}
+	_, err = btd.BatchCombine(cts, d, true)
+	require.Error(t, err)
+}
+
+func TestBatchCombineParallel(t *testing.T) {
+	suite, btd, sks, pk := setup(8, 4, 2)
+	cts, ms := encryptBatch(t, suite, btd, pk)
+	d := make([]*elgamal.DecShare, btd.T)
+	for i := range d {
+		var err error
+		d[i], err = btd.BatchDec(cts, sks[i], true)
+		require.NoError(t, err)
+	}
+	seq, err := btd.BatchCombine(cts, d, true)
+	require.NoError(t, err)
+	for _, workers := range []int{0, 1, 3, 16} {
+		res, err := btd.BatchCombineParallel(context.Background(), cts, d, true, workers)
+		require.NoError(t, err)
+		require.Len(t, res, len(ms))
+		for i := range ms {
+			require.True(t, seq[i].Equal(res[i]), "wrong message on index %d with %d workers", i, workers)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = btd.BatchCombineParallel(ctx, cts, d, true, 2)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestBatchCombineSub(t *testing.T) {
+	suite, btd, sks, pk := setup(10, 4, 2)
+	cts, ms := encryptBatch(t, suite, btd, pk)
+	require.Equal(t, 3, be.SqrtAlpha(btd.B, 1))
+	require.Equal(t, 6, be.SqrtAlpha(btd.B, 2))
+
+	for _, alpha := range []int{1, be.SqrtAlpha(btd.B, 1), be.SqrtAlpha(btd.B, 2), btd.B} {
+		p, err := be.NewPartition(cts, alpha)
+		require.NoError(t, err)
+		require.Len(t, p, alpha)
+		require.Equal(t, btd.B, p.Len())
+		for _, sub := range p {
+			require.InDelta(t, float64(btd.B)/float64(alpha), len(sub), 1)
+		}
+		d := make([][]*elgamal.DecShare, btd.T)
+		for k := range d {
+			d[k], err = btd.BatchDecSub(p, sks[k], true)
+			require.NoError(t, err)
+			require.Len(t, d[k], alpha)
+		}
+		res, err := btd.BatchCombineSub(p, d, true)
+		require.NoError(t, err)
+		require.Len(t, res, len(ms))
+		for i := range ms {
+			require.True(t, ms[i].Equal(res[i]), "wrong message on index %d with alpha %d", i, alpha)
+		}
+
+		d[1] = d[1][1:]
+		_, err = btd.BatchCombineSub(p, d, true)
+		require.Error(t, err)
+	}
+
+	_, err := be.NewPartition(cts, 0)
+	require.Error(t, err)
+	_, err = be.NewPartition(cts, btd.B+1)
+	require.Error(t, err)
+}
+
+func TestPartialBatch(t *testing.T) {
+	suite, btd, sks, pk := setup(8, 4, 2)
+	cts, ms := encryptBatch(t, suite, btd, pk)
+	batch := []be.CT{cts[6], cts[1], cts[4]}
+	empty, err := btd.EmptySlots(batch)
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 2, 3, 5, 7}, empty)
+
+	d := make([]*elgamal.DecShare, btd.T)
+	for k := range d {
+		d[k], err = btd.BatchDec(batch, sks[k], true)
+		require.NoError(t, err)
+	}
+	res, err := btd.BatchCombine(batch, d, true)
+	require.NoError(t, err)
+	for k, i := range []int{6, 1, 4} {
+		require.True(t, ms[i].Equal(res[k]), "wrong message on index %d", i)
+	}
+
+	// A second ciphertext on index 4 is rejected everywhere.
+	other, err := btd.Enc(pk, 4, suite.PickGT(), nil)
+	require.NoError(t, err)
+	dup := append(batch, other)
+	_, err = btd.EmptySlots(dup)
+	require.ErrorIs(t, err, be.ErrDuplicateIndex)
+	_, err = btd.BatchDec(dup, sks[0], false)
+	require.ErrorIs(t, err, be.ErrDuplicateIndex)
+	_, err = btd.BatchCombine(dup, d, false)
+	require.ErrorIs(t, err, be.ErrDuplicateIndex)
+	valid, errs := btd.FilterCTs(dup)
+	require.Equal(t, batch, valid)
+	require.Len(t, errs, 1)
+	require.ErrorIs(t, errs[0], be.ErrDuplicateIndex)
+}
+
+func TestBatchCombineRobust(t *testing.T) {
+	suite, btd, sks, pk := setup(8, 7, 3)
+	cts, ms := encryptBatch(t, suite, btd, pk)
+	d := make([]*elgamal.DecShare, 5)
+	for i := range d {
+		var err error
+		d[i], err = btd.BatchDec(cts, sks[i], true)
+		require.NoError(t, err)
+	}
+	// Committee member 2 sends member 0's share, member 3 a share for another batch.
+	d[2] = &elgamal.DecShare{PubShare: share.PubShare{I: 2, V: d[0].V}, Proof: d[0].Proof}
+	d[3], _ = btd.BatchDec(cts[1:], sks[3], true)
+	res, report, err := btd.BatchCombineRobust(cts, d, true)
+	require.NoError(t, err)
+	require.Equal(t, []uint32{0, 1, 4}, report.Used)
+	require.Equal(t, []uint32{2, 3}, report.Invalid)
+	for i := range ms {
+		require.True(t, ms[i].Equal(res[i]), "wrong message on index %d", i)
+	}
+	_, report, err = btd.BatchCombineRobust(cts, d[1:4], true)
+	require.Error(t, err)
+	require.Equal(t, []uint32{2, 3}, report.Invalid)
+}
+
+func TestBatchCombineOpt(t *testing.T) {
+	suite, btd, sks, pk := setup(8, 10, 5)
+	cts, ms := encryptBatch(t, suite, btd, pk)
+	ds := make([][]*elgamal.DecShare, btd.T)
+	for i := 0; i < btd.T; i++ {
+		var err error
+		ds[i], err = btd.BatchDecOpt(cts, sks[i], true)
+		require.NoError(t, err)
+	}
+	res, err := btd.BatchCombineOpt(cts, ds, false)
+	require.NoError(t, err)
+	for i := range ms {
+		require.True(t, ms[i].Equal(res[i]), "wrong message on index %d", i)
+	}
+}
+
+func TestCTEncoding(t *testing.T) {
+	for _, suite := range allSuites() {
+		t.Run(suite.Name(), func(t *testing.T) {
+			testCTEncoding(t, suite)
+		})
+	}
+}
+
+func testCTEncoding(t *testing.T, suite curves.Suite) {
+	btd, sks, pk := setupSuite(suite, 8, 10, 5)
+	cts, ms := encryptBatch(t, suite, btd, pk)
+	dec := make([]be.CT, len(cts))
+	for i, ct := range cts {
+		data, err := ct.MarshalBinary()
+		require.NoError(t, err)
+		dec[i], err = btd.UnmarshalCT(data)
+		require.NoError(t, err)
+		require.Equal(t, ct.Index(), dec[i].Index())
+		again, err := dec[i].MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, data, again)
+	}
+	d := make([]*elgamal.DecShare, btd.T)
+	for i := 0; i < btd.T; i++ {
+		var err error
+		d[i], err = btd.BatchDec(dec, sks[i], true)
+		require.NoError(t, err)
+	}
+	res, err := btd.BatchCombine(dec, d, true)
+	require.NoError(t, err)
+	for i := range ms {
+		require.True(t, ms[i].Equal(res[i]), "wrong message on index %d", i)
+	}
+
+	data, err := cts[3].MarshalBinary()
+	require.NoError(t, err)
+	// Truncated and extended encodings are rejected.
+	_, err = btd.UnmarshalCT(data[:len(data)-1])
+	require.Error(t, err)
+	_, err = btd.UnmarshalCT(append(append([]byte{}, data...), 0))
+	require.Error(t, err)
+	// Unknown versions are rejected.
+	bad := append([]byte{}, data...)
+	bad[0] = 0xff
+	_, err = btd.UnmarshalCT(bad)
+	require.Error(t, err)
+	// Indices outside of the CRS domain are rejected.
+	small := be.NewBTD(suite, 2)
+	_, err = small.UnmarshalCT(data)
+	require.Error(t, err)
+	// Malformed points are rejected: flip a byte in the encoding of kp.
+	kpOff, _ := field(data, 2+int(data[1])+4, 2)
+	bad = append([]byte{}, data...)
+	bad[kpOff+5] ^= 0xff
+	_, err = btd.UnmarshalCT(bad)
+	require.Error(t, err)
+	// A failed decoding leaves the ciphertext unchanged.
+	ct := dec[3]
+	require.Error(t, ct.UnmarshalBinary(bad))
+	again, err := ct.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, data, again)
+	// Lengths beyond the encoding are rejected, also those that do not fit an int32.
+	adOff := 2 + int(data[1]) + 4
+	bad = append([]byte{}, data...)
+	binary.BigEndian.PutUint32(bad[adOff:], 1<<31)
+	_, err = btd.UnmarshalCT(bad)
+	require.Error(t, err)
+}
+
+func TestKeyEncoding(t *testing.T) {
+	suite, btd, sks, pk := setup(4, 5, 3)
+	key, err := btd.MarshalKey()
+	require.NoError(t, err)
+	// A combiner that only knows the CRS loads the public key of the committee.
+	other := be.NewBTDWithCRS(suite, btd.CRS())
+	_, err = other.MarshalKey()
+	require.ErrorIs(t, err, be.ErrNoKey)
+	require.NoError(t, other.UnmarshalKey(key))
+	require.Equal(t, btd.T, other.T)
+	require.Equal(t, btd.N, other.N)
+	again, err := other.MarshalKey()
+	require.NoError(t, err)
+	require.Equal(t, key, again)
+	_, err = other.UnmarshalKeyShare(nil)
+	require.Error(t, err)
+	require.Error(t, other.UnmarshalKey(key[:len(key)-1]))
+
+	cts, ms := encryptBatch(t, suite, btd, pk)
+	data, err := be.MarshalBatch(cts)
+	require.NoError(t, err)
+	batch, err := other.UnmarshalBatch(data)
+	require.NoError(t, err)
+	require.Len(t, batch, len(cts))
+	_, err = other.UnmarshalBatch(data[:len(data)-1])
+	require.Error(t, err)
+
+	d := make([]*elgamal.DecShare, btd.T)
+	for k := range d {
+		data, err := btd.MarshalKeyShare(sks[k])
+		require.NoError(t, err)
+		sk, err := other.UnmarshalKeyShare(data)
+		require.NoError(t, err)
+		require.Equal(t, sks[k].I, sk.I)
+		ds, err := other.BatchDec(batch, sk, true)
+		require.NoError(t, err)
+		// Decryption shares are sent to the combiner over the wire.
+		data, err = btd.MarshalDecShare(ds)
+		require.NoError(t, err)
+		d[k], err = other.UnmarshalDecShare(data)
+		require.NoError(t, err)
+		require.NoError(t, other.VerifyShare(batch, d[k]))
+	}
+	res, err := other.BatchCombine(batch, d, true)
+	require.NoError(t, err)
+	for i := range ms {
+		require.True(t, ms[i].Equal(res[i]), "wrong message on index %d", i)
+	}
+
+	// A decryption share of another suite is rejected, even with elements of the same size.
+	circl, err := curves.Lookup("bls12381-circl")
+	require.NoError(t, err)
+	data, err = be.NewBTD(circl, 4).MarshalDecShare(d[0])
+	require.NoError(t, err)
+	_, err = other.UnmarshalDecShare(data)
+	require.ErrorContains(t, err, "suite bls12381-circl")
+
+	// A key share of another committee is rejected.
+	_, _, foreign, _ := setup(4, 5, 3)
+	data, err = btd.MarshalKeyShare(foreign[0])
+	require.NoError(t, err)
+	_, err = other.UnmarshalKeyShare(data)
+	require.Error(t, err)
+}
+
+// field returns the offset and length of the k-th length-prefixed field of an encoding whose fields start at off.
+func field(data []byte, off, k int) (int, int) {
+	for ; k > 0; k-- {
+		off += 4 + int(binary.BigEndian.Uint32(data[off:]))
+	}
+	return off + 4, int(binary.BigEndian.Uint32(data[off:]))
+}
+
+// forge replaces the k-th field of the proof of ct by the one of other. The responses kHat (k = 3) and uHat (k = 4)
+// are not hashed, so replacing one of them breaks the equations B and A, respectively.
+func forge(t *testing.T, btd *be.BTD, ct, other be.CT, k int) be.CT {
+	data, err := ct.MarshalBinary()
+	require.NoError(t, err)
+	otherData, err := other.MarshalBinary()
+	require.NoError(t, err)
+	proofOff, _ := field(data, 2+int(data[1])+4, 5)
+	off, n := field(data, proofOff+1, k)
+	copy(data[off:off+n], otherData[off:off+n])
+	forged, err := btd.UnmarshalCT(data)
+	require.NoError(t, err)
+	return forged
+}
+
+func TestVerifyCT(t *testing.T) {
+	suite, btd, _, pk := setup(4, 4, 2)
+	cts, _ := encryptBatch(t, suite, btd, pk)
+	require.NoError(t, btd.VerifyCT(cts[2]))
+	other, err := btd.Enc(pk, 2, suite.PickGT(), nil)
+	require.NoError(t, err)
+
+	for eq, k := range map[string]int{"A": 4, "B": 3} {
+		ct := forge(t, btd, cts[2], other, k)
+		err = btd.VerifyCT(ct)
+		var pErr *be.ProofError
+		require.ErrorAs(t, err, &pErr)
+		require.Equal(t, 2, pErr.Index)
+		require.Equal(t, eq, pErr.Equation)
+
+		// Batch operations reject the ciphertext instead of panicking, and filtering removes it.
+		batch := []be.CT{cts[0], ct, cts[3]}
+		_, err = btd.SumEGCt(batch, true)
+		require.ErrorAs(t, err, &pErr)
+		valid, errs := btd.FilterCTs(batch)
+		require.Len(t, valid, 2)
+		require.Equal(t, 3, valid[1].Index())
+		require.Len(t, errs, 1)
+	}
+	require.Error(t, btd.VerifyCT(be.CT{}))
+}
+
+func TestVerifyBatch(t *testing.T) {
+	suite, btd, _, pk := setup(16, 4, 2)
+	cts, _ := encryptBatch(t, suite, btd, pk)
+	require.NoError(t, btd.VerifyBatch(cts))
+	require.NoError(t, btd.VerifyBatch(cts[5:6]))
+	require.NoError(t, btd.VerifyBatch(nil))
+
+	// The invalid proofs are located.
+	bad := append([]be.CT{}, cts...)
+	for _, i := range []int{3, 4, 11} {
+		other, err := btd.Enc(pk, i, suite.PickGT(), nil)
+		require.NoError(t, err)
+		bad[i] = forge(t, btd, cts[i], other, 3+i%2)
+	}
+	err := btd.VerifyBatch(bad)
+	require.Error(t, err)
+	var found []int
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var pErr *be.ProofError
+		require.ErrorAs(t, e, &pErr)
+		found = append(found, pErr.Index)
+	}
+	require.Equal(t, []int{3, 4, 11}, found)
+}
+
+func TestBatchCombineDKG(t *testing.T) {
+	suite := curves.NewSuite(kilic.NewBLS12381Suite())
+	btd := be.NewBTD(suite, 4)
+	keys, pk, err := btd.KeyGenDKG(7, 4)
+	require.NoError(t, err)
+	cts, ms := encryptBatch(t, suite, btd, pk)
+	// Any t committee members can decrypt.
+	d := make([]*elgamal.DecShare, 0, btd.T)
+	for _, i := range []int{6, 1, 3, 4} {
+		s, err := btd.BatchDec(cts, keys[i].Share, true)
+		require.NoError(t, err)
+		d = append(d, s)
+	}
+	res, err := btd.BatchCombine(cts, d, true)
+	require.NoError(t, err)
+	for i := range ms {
+		require.True(t, ms[i].Equal(res[i]), "wrong message on index %d", i)
+	}
+}
+
+func TestBatchCombineBytes(t *testing.T) {
+	_, btd, sks, pk := setup(4, 4, 2)
+	msgs := [][]byte{[]byte("transfer 10 to alice"), {}, make([]byte, 4096), []byte("swap")}
+	cts := make([]be.BytesCT, len(msgs))
+	for i, m := range msgs {
+		ct, err := btd.EncBytes(pk, i, m, nil)
+		require.NoError(t, err)
+		// The ciphertexts are sent over the wire.
+		data, err := ct.MarshalBinary()
+		require.NoError(t, err)
+		cts[i], err = btd.UnmarshalBytesCT(data)
+		require.NoError(t, err)
+	}
+	d := make([]*elgamal.DecShare, btd.T)
+	for i := range d {
+		var err error
+		d[i], err = btd.BatchDecBytes(cts, sks[i], true)
+		require.NoError(t, err)
+	}
+	res, err := btd.BatchCombineBytes(cts, d, true)
+	require.NoError(t, err)
+	for i := range msgs {
+		require.Equal(t, msgs[i], res[i], "wrong payload on index %d", i)
+	}
+
+	// A payload moved to another ciphertext does not decrypt, and neither does a tampered one.
+	data0, err := cts[0].MarshalBinary()
+	require.NoError(t, err)
+	data3, err := cts[3].MarshalBinary()
+	require.NoError(t, err)
+	kemLen := 4 + int(binary.BigEndian.Uint32(data3))
+	payload0 := data0[4+int(binary.BigEndian.Uint32(data0)):]
+	moved, err := btd.UnmarshalBytesCT(append(append([]byte{}, data3[:kemLen]...), payload0...))
+	require.NoError(t, err)
+	data0[len(data0)-1] ^= 1
+	tampered, err := btd.UnmarshalBytesCT(data0)
+	require.NoError(t, err)
+	res, err = btd.BatchCombineBytes([]be.BytesCT{tampered, cts[1], cts[2], moved}, d, true)
+	require.Error(t, err)
+	require.Nil(t, res[0])
+	require.Equal(t, msgs[1], res[1])
+	require.Nil(t, res[3])
+}
+
+func TestAssociatedData(t *testing.T) {
+	suite, btd, _, pk := setup(4, 4, 2)
+	ad := []byte("epoch-1")
+	ct, err := btd.Enc(pk, 1, suite.PickGT(), ad)
+	require.NoError(t, err)
+	data, err := ct.MarshalBinary()
+	require.NoError(t, err)
+	ct, err = btd.UnmarshalCT(data)
+	require.NoError(t, err)
+	require.Equal(t, ad, ct.AD())
+	require.NoError(t, btd.VerifyCT(ct))
+	require.NoError(t, be.CheckAD([]be.CT{ct}, ad))
+	require.ErrorIs(t, be.CheckAD([]be.CT{ct}, []byte("epoch-2")), be.ErrADMismatch)
+
+	// Rebinding the ciphertext to another epoch invalidates its proof.
+	off, n := field(data, 2+int(data[1])+4, 0)
+	require.Equal(t, ad, data[off:off+n])
+	copy(data[off:], "epoch-2")
+	replayed, err := btd.UnmarshalCT(data)
+	require.NoError(t, err)
+	var pErr *be.ProofError
+	require.ErrorAs(t, btd.VerifyCT(replayed), &pErr)
+}
+
+// TestConcurrentUse shares one BTD between goroutines that encrypt, verify, decrypt and combine at the same time.
+// Run with -race to check the concurrency contract of BTD.
+func TestConcurrentUse(t *testing.T) {
+	suite, btd, sks, pk := setup(4, 4, 2)
+	cts, ms := encryptBatch(t, suite, btd, pk)
+	d := make([]*elgamal.DecShare, btd.T)
+	for i := range d {
+		var err error
+		d[i], err = btd.BatchDec(cts, sks[i], true)
+		require.NoError(t, err)
+	}
+	workers := 4
+	errs := make(chan error, 4*workers)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			ct, err := btd.Enc(pk, w%btd.B, suite.PickGT(), nil)
+			if err == nil {
+				err = btd.VerifyCT(ct)
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- btd.VerifyBatch(cts)
+		}()
+		go func() {
+			defer wg.Done()
+			s, err := btd.BatchDec(cts, sks[w%len(sks)], true)
+			if err == nil {
+				err = btd.VerifyShare(cts, s)
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			res, err := btd.BatchCombine(cts, d, true)
+			for i := 0; err == nil && i < len(ms); i++ {
+				if !ms[i].Equal(res[i]) {
+					err = fmt.Errorf("wrong message on index %d", i)
+				}
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+}
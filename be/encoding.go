@@ -0,0 +1,372 @@
+package be
+
+import (
+	"btd/elgamal"
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go.dedis.ch/kyber/v4"
+	"math"
+)
+
+// Wire format of a ciphertext, all integers are big-endian:
+//
+//	version uint8
+//	suite   uint8 length || suite name
+//	index   uint32
+//	ad      uint32 length || associated data
+//	gamma   uint32 length || GT element
+//	kp      uint32 length || G1 element
+//	A       uint32 length || G1 element
+//	B       uint32 length || G1 element
+//	proof   uint32 length || proof
+//
+// A proof is encoded as version || Ap || Bp || yp || kHat || uHat, where every element is prefixed by its uint32
+// length.
+const wireVersion = 2
+
+var (
+	_ encoding.BinaryMarshaler   = CT{}
+	_ encoding.BinaryUnmarshaler = (*CT)(nil)
+	_ encoding.BinaryMarshaler   = Proof{}
+	_ encoding.BinaryUnmarshaler = (*Proof)(nil)
+	_ encoding.BinaryMarshaler   = BytesCT{}
+	_ encoding.BinaryUnmarshaler = (*BytesCT)(nil)
+)
+
+type encoder struct {
+	buf []byte
+	err error
+}
+
+func (e *encoder) uint8(v uint8) {
+	e.buf = append(e.buf, v)
+}
+
+func (e *encoder) uint32(v uint32) {
+	e.buf = binary.BigEndian.AppendUint32(e.buf, v)
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.uint32(uint32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) marshaler(m encoding.BinaryMarshaler) {
+	if e.err != nil {
+		return
+	}
+	b, err := m.MarshalBinary()
+	if err != nil {
+		e.err = err
+		return
+	}
+	e.bytes(b)
+}
+
+type decoder struct {
+	buf []byte
+	err error
+}
+
+var errShortBuffer = errors.New("buffer too short")
+
+func (d *decoder) uint8() uint8 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 1 {
+		d.err = errShortBuffer
+		return 0
+	}
+	v := d.buf[0]
+	d.buf = d.buf[1:]
+	return v
+}
+
+func (d *decoder) uint32() uint32 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 4 {
+		d.err = errShortBuffer
+		return 0
+	}
+	v := binary.BigEndian.Uint32(d.buf)
+	d.buf = d.buf[4:]
+	return v
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errShortBuffer
+		return nil
+	}
+	v := d.buf[:n]
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uint32()
+	// Compared before the conversion, as lengths of 2^31 and more are negative ints on 32-bit platforms.
+	if d.err == nil && uint64(n) > uint64(len(d.buf)) {
+		d.err = errShortBuffer
+		return nil
+	}
+	return d.next(int(n))
+}
+
+func (d *decoder) unmarshaler(name string, u encoding.BinaryUnmarshaler) {
+	b := d.bytes()
+	if d.err != nil {
+		return
+	}
+	if err := u.UnmarshalBinary(b); err != nil {
+		d.err = fmt.Errorf("invalid %s: %w", name, err)
+	}
+}
+
+func (d *decoder) finish() error {
+	if d.err == nil && len(d.buf) != 0 {
+		d.err = fmt.Errorf("%d trailing bytes", len(d.buf))
+	}
+	return d.err
+}
+
+// MarshalBinary encodes the proof in the canonical wire format.
+func (p Proof) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.uint8(wireVersion)
+	e.marshaler(p.Ap)
+	e.marshaler(p.Bp)
+	e.marshaler(p.yp)
+	e.marshaler(p.kHat)
+	e.marshaler(p.uHat)
+	return e.buf, e.err
+}
+
+// UnmarshalBinary decodes a proof from the canonical wire format. The proof must have been allocated with
+// BTD.NewProof. On error, p is left unchanged.
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	if p.Ap == nil || p.Bp == nil || p.yp == nil || p.kHat == nil || p.uHat == nil {
+		return errors.New("proof is not allocated")
+	}
+	d := &decoder{buf: data}
+	if v := d.uint8(); d.err == nil && v != wireVersion {
+		return fmt.Errorf("unsupported proof version %d", v)
+	}
+	dec := Proof{Ap: p.Ap.Clone(), Bp: p.Bp.Clone(), yp: p.yp.Clone(), kHat: p.kHat.Clone(), uHat: p.uHat.Clone()}
+	d.unmarshaler("Ap", dec.Ap)
+	d.unmarshaler("Bp", dec.Bp)
+	d.unmarshaler("yp", dec.yp)
+	d.unmarshaler("kHat", dec.kHat)
+	d.unmarshaler("uHat", dec.uHat)
+	if err := d.finish(); err != nil {
+		return fmt.Errorf("decoding proof: %w", err)
+	}
+	*p = dec
+	return nil
+}
+
+// MarshalBinary encodes the ciphertext in the canonical wire format.
+func (ct CT) MarshalBinary() ([]byte, error) {
+	if ct.suite == nil {
+		return nil, errors.New("ciphertext is not bound to a suite")
+	}
+	name := ct.suite.Name()
+	if len(name) > math.MaxUint8 {
+		return nil, fmt.Errorf("suite name too long: %s", name)
+	}
+	if ct.i < 0 || int64(ct.i) > math.MaxUint32 {
+		return nil, fmt.Errorf("index out of range: %d", ct.i)
+	}
+	e := &encoder{}
+	e.uint8(wireVersion)
+	e.uint8(uint8(len(name)))
+	e.buf = append(e.buf, name...)
+	e.uint32(uint32(ct.i))
+	if int64(len(ct.ad)) > math.MaxUint32 {
+		return nil, errors.New("associated data too long")
+	}
+	e.bytes(ct.ad)
+	e.marshaler(ct.gamma)
+	e.marshaler(ct.kp)
+	e.marshaler(ct.c.A)
+	e.marshaler(ct.c.B)
+	e.marshaler(ct.pi)
+	return e.buf, e.err
+}
+
+// UnmarshalBinary decodes a ciphertext from the canonical wire format. The ciphertext must have been created with
+// BTD.NewCT, which binds it to the suite it is decoded in. Use BTD.UnmarshalCT to also check the index against the
+// size of the CRS. On error, ct is left unchanged.
+func (ct *CT) UnmarshalBinary(data []byte) error {
+	if ct.suite == nil {
+		return errors.New("ciphertext is not bound to a suite")
+	}
+	d := &decoder{buf: data}
+	if v := d.uint8(); d.err == nil && v != wireVersion {
+		return fmt.Errorf("unsupported ciphertext version %d", v)
+	}
+	name := string(d.next(int(d.uint8())))
+	if d.err == nil && name != ct.suite.Name() {
+		return fmt.Errorf("ciphertext for suite %s cannot be decoded in suite %s", name, ct.suite.Name())
+	}
+	i := d.uint32()
+	if d.err == nil && uint64(i) > math.MaxInt32 {
+		return fmt.Errorf("index out of range: %d", i)
+	}
+	// The ciphertext is decoded into dec, so that ct is only overwritten once decoding succeeded.
+	dec := CT{
+		suite: ct.suite,
+		i:     int(i),
+		ad:    bytes.Clone(d.bytes()),
+		gamma: ct.suite.GT().Point(),
+		kp:    ct.suite.G1().Point(),
+		c:     elgamal.CT{A: ct.suite.G1().Point(), B: ct.suite.G1().Point()},
+		pi:    newProof(ct.suite.G1()),
+	}
+	d.unmarshaler("gamma", dec.gamma)
+	d.unmarshaler("kp", dec.kp)
+	d.unmarshaler("A", dec.c.A)
+	d.unmarshaler("B", dec.c.B)
+	d.unmarshaler("proof", &dec.pi)
+	if err := d.finish(); err != nil {
+		return fmt.Errorf("decoding ciphertext: %w", err)
+	}
+	*ct = dec
+	return nil
+}
+
+func newProof(g kyber.Group) Proof {
+	return Proof{
+		Ap:   g.Point(),
+		Bp:   g.Point(),
+		yp:   g.Point(),
+		kHat: g.Scalar(),
+		uHat: g.Scalar(),
+	}
+}
+
+// NewProof allocates an empty proof that can be decoded with UnmarshalBinary.
+func (b *BTD) NewProof() Proof {
+	return newProof(b.suite.G1())
+}
+
+// NewCT allocates an empty ciphertext bound to the suite of b that can be decoded with UnmarshalBinary.
+func (b *BTD) NewCT() CT {
+	return CT{suite: b.suite}
+}
+
+// UnmarshalCT decodes a ciphertext from the canonical wire format and checks that its index is within the domain
+// of the CRS.
+func (b *BTD) UnmarshalCT(data []byte) (CT, error) {
+	ct := b.NewCT()
+	if err := ct.UnmarshalBinary(data); err != nil {
+		return CT{}, err
+	}
+	if ct.i >= b.B {
+		return CT{}, fmt.Errorf("ciphertext index out of domain. Domain: [0, %d-1], index: %d", b.B, ct.i)
+	}
+	return ct, nil
+}
+
+// MarshalBinary encodes the hybrid ciphertext as the encoding of its BTD ciphertext, prefixed by its uint32 length,
+// followed by the AES-GCM ciphertext of the payload.
+func (ct BytesCT) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+	e.marshaler(ct.kem)
+	e.buf = append(e.buf, ct.dem...)
+	return e.buf, e.err
+}
+
+// UnmarshalBinary decodes a hybrid ciphertext. The ciphertext must have been created with BTD.NewBytesCT.
+func (ct *BytesCT) UnmarshalBinary(data []byte) error {
+	d := &decoder{buf: data}
+	d.unmarshaler("ciphertext", &ct.kem)
+	if d.err != nil {
+		return fmt.Errorf("decoding hybrid ciphertext: %w", d.err)
+	}
+	ct.dem = append([]byte{}, d.buf...)
+	return nil
+}
+
+// NewBytesCT allocates an empty hybrid ciphertext bound to the suite of b that can be decoded with
+// UnmarshalBinary.
+func (b *BTD) NewBytesCT() BytesCT {
+	return BytesCT{kem: b.NewCT()}
+}
+
+// UnmarshalBytesCT decodes a hybrid ciphertext and checks that its index is within the domain of the CRS.
+func (b *BTD) UnmarshalBytesCT(data []byte) (BytesCT, error) {
+	ct := b.NewBytesCT()
+	if err := ct.UnmarshalBinary(data); err != nil {
+		return BytesCT{}, err
+	}
+	if ct.kem.i >= b.B {
+		return BytesCT{}, fmt.Errorf("ciphertext index out of domain. Domain: [0, %d-1], index: %d", b.B, ct.kem.i)
+	}
+	return ct, nil
+}
+
+// A batch is encoded as the uint32 number of ciphertexts, followed by the encodings of the ciphertexts, each prefixed
+// by its uint32 length.
+func marshalBatch[T encoding.BinaryMarshaler](cts []T) ([]byte, error) {
+	e := &encoder{}
+	e.uint32(uint32(len(cts)))
+	for _, ct := range cts {
+		e.marshaler(ct)
+	}
+	return e.buf, e.err
+}
+
+func unmarshalBatch[T any](data []byte, unmarshal func([]byte) (T, error)) ([]T, error) {
+	d := &decoder{buf: data}
+	n := d.uint32()
+	// Every ciphertext takes at least its length prefix, which bounds the allocation for malformed batches.
+	if d.err == nil && uint64(n) > uint64(len(d.buf)/4) {
+		return nil, fmt.Errorf("decoding batch: %d ciphertexts in %d bytes", n, len(d.buf))
+	}
+	cts := make([]T, n)
+	for k := range cts {
+		b := d.bytes()
+		if d.err != nil {
+			break
+		}
+		ct, err := unmarshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("decoding ciphertext %d of batch: %w", k, err)
+		}
+		cts[k] = ct
+	}
+	if err := d.finish(); err != nil {
+		return nil, fmt.Errorf("decoding batch: %w", err)
+	}
+	return cts, nil
+}
+
+// MarshalBatch encodes the batch cts.
+func MarshalBatch(cts []CT) ([]byte, error) {
+	return marshalBatch(cts)
+}
+
+// UnmarshalBatch decodes a batch encoded with MarshalBatch, see UnmarshalCT.
+func (b *BTD) UnmarshalBatch(data []byte) ([]CT, error) {
+	return unmarshalBatch(data, b.UnmarshalCT)
+}
+
+// MarshalBytesBatch encodes the batch of hybrid ciphertexts cts.
+func MarshalBytesBatch(cts []BytesCT) ([]byte, error) {
+	return marshalBatch(cts)
+}
+
+// UnmarshalBytesBatch decodes a batch encoded with MarshalBytesBatch, see UnmarshalBytesCT.
+func (b *BTD) UnmarshalBytesBatch(data []byte) ([]BytesCT, error) {
+	return unmarshalBatch(data, b.UnmarshalBytesCT)
+}
@@ -0,0 +1,119 @@
+package be
+
+import (
+	"btd/elgamal"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/share"
+)
+
+// demLabel separates the key derivation of the hybrid mode from other uses of the hash function.
+const demLabel = "btd-dem-aes256gcm"
+
+// BytesCT is a hybrid ciphertext of a byte payload. The BTD ciphertext kem encrypts a random GT element R, which is
+// hashed into the key of the AES-256-GCM ciphertext dem of the payload. The encoding of kem is the associated data of
+// dem, so a payload cannot be moved to another BTD ciphertext.
+type BytesCT struct {
+	kem CT
+	dem []byte // nonce || AES-GCM ciphertext
+}
+
+// KEM returns the BTD ciphertext of the hybrid ciphertext, which is what the committee decrypts.
+func (ct BytesCT) KEM() CT {
+	return ct.kem
+}
+
+// Index returns the index of the CRS the ciphertext was encrypted for.
+func (ct BytesCT) Index() int {
+	return ct.kem.i
+}
+
+// EncBytes encrypts the payload msg of arbitrary length under pk on index i, bound to the associated data ad as in
+// Enc.
+func (b *BTD) EncBytes(pk kyber.Point, i int, msg, ad []byte) (BytesCT, error) {
+	R := b.suite.PickGT()
+	kem, err := b.Enc(pk, i, R, ad)
+	if err != nil {
+		return BytesCT{}, err
+	}
+	aead, demAD, err := b.dem(kem, R)
+	if err != nil {
+		return BytesCT{}, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(msg)+aead.Overhead())
+	b.suite.RandomStream().XORKeyStream(nonce, nonce)
+	return BytesCT{kem: kem, dem: aead.Seal(nonce, nonce, msg, demAD)}, nil
+}
+
+// DecBytes decrypts the payload of ct with the GT element R recovered from its BTD ciphertext, e.g., by
+// BatchCombine.
+func (b *BTD) DecBytes(ct BytesCT, R kyber.Point) ([]byte, error) {
+	aead, demAD, err := b.dem(ct.kem, R)
+	if err != nil {
+		return nil, err
+	}
+	if len(ct.dem) < aead.NonceSize() {
+		return nil, errors.New("payload too short")
+	}
+	nonce, sealed := ct.dem[:aead.NonceSize()], ct.dem[aead.NonceSize():]
+	// A non-nil result tells an empty payload apart from a failed decryption in BatchCombineBytes.
+	return aead.Open([]byte{}, nonce, sealed, demAD)
+}
+
+// dem returns the AEAD keyed with sha256(demLabel || R) and the associated data of the BTD ciphertext kem.
+func (b *BTD) dem(kem CT, R kyber.Point) (cipher.AEAD, []byte, error) {
+	demAD, err := kem.MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+	h := sha256.New()
+	h.Write([]byte(demLabel))
+	if _, err := R.MarshalTo(h); err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(h.Sum(nil))
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, demAD, nil
+}
+
+// KEMs returns the BTD ciphertexts of the hybrid ciphertexts cts.
+func KEMs(cts []BytesCT) []CT {
+	kems := make([]CT, len(cts))
+	for i, ct := range cts {
+		kems[i] = ct.kem
+	}
+	return kems
+}
+
+// BatchDecBytes computes the decryption share of the batch of hybrid ciphertexts cts, see BatchDec.
+func (b *BTD) BatchDecBytes(cts []BytesCT, sk *share.PriShare, verify bool) (*elgamal.DecShare, error) {
+	return b.BatchDec(KEMs(cts), sk, verify)
+}
+
+// BatchCombineBytes combines the decryption shares d of the batch cts and decrypts the payloads of all ciphertexts
+// in the batch. A payload that fails to decrypt does not affect the others: its entry is nil and its error is
+// returned joined with the errors of the other failed payloads.
+func (b *BTD) BatchCombineBytes(cts []BytesCT, d []*elgamal.DecShare, verify bool) ([][]byte, error) {
+	Rs, err := b.BatchCombine(KEMs(cts), d, verify)
+	if err != nil {
+		return nil, err
+	}
+	msgs := make([][]byte, len(cts))
+	var errs []error
+	for i, ct := range cts {
+		if msgs[i], err = b.DecBytes(ct, Rs[i]); err != nil {
+			errs = append(errs, fmt.Errorf("payload on index %d: %w", ct.kem.i, err))
+		}
+	}
+	return msgs, errors.Join(errs...)
+}
@@ -0,0 +1,159 @@
+package be
+
+import (
+	"btd/elgamal"
+	"errors"
+	"fmt"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/share"
+	"math"
+)
+
+// Wire format of the public key of a committee, all integers are big-endian:
+//
+//	version uint8
+//	suite   uint8 length || suite name
+//	n       uint32
+//	t       uint32
+//	commits t times uint32 length || G1 element
+//
+// and of the key share of a committee member:
+//
+//	version uint8
+//	suite   uint8 length || suite name
+//	index   uint32
+//	share   uint32 length || scalar
+//
+// and of a decryption share:
+//
+//	version uint8
+//	suite   uint8 length || suite name
+//	share   the encoding of elgamal.DecShare.MarshalBinary
+//
+// The commitments are those of the committee's sharing of the secret key, so the public key also determines the
+// verification keys of all members.
+const keyVersion = 1
+
+// ErrNoKey is returned when encoding the key of a BTD whose key has not been set.
+var ErrNoKey = errors.New("key has not been set")
+
+func (e *encoder) suite(name string) {
+	if len(name) > math.MaxUint8 {
+		e.err = fmt.Errorf("suite name too long: %s", name)
+		return
+	}
+	e.uint8(uint8(len(name)))
+	e.buf = append(e.buf, name...)
+}
+
+// decodeHeader decodes the version and the suite name of a key and checks them against the suite of b.
+func (b *BTD) decodeHeader(d *decoder, what string) {
+	if v := d.uint8(); d.err == nil && v != keyVersion {
+		d.err = fmt.Errorf("unsupported %s version %d", what, v)
+	}
+	name := string(d.next(int(d.uint8())))
+	if d.err == nil && name != b.suite.Name() {
+		d.err = fmt.Errorf("%s for suite %s cannot be decoded in suite %s", what, name, b.suite.Name())
+	}
+}
+
+// MarshalKey encodes the public key of b.
+func (b *BTD) MarshalKey() ([]byte, error) {
+	if b.eg.Commits == nil {
+		return nil, ErrNoKey
+	}
+	_, commits := b.eg.Commits.Info()
+	e := &encoder{}
+	e.uint8(keyVersion)
+	e.suite(b.suite.Name())
+	e.uint32(uint32(b.N))
+	e.uint32(uint32(len(commits)))
+	for _, c := range commits {
+		e.marshaler(c)
+	}
+	return e.buf, e.err
+}
+
+// UnmarshalKey decodes a public key encoded with MarshalKey and sets it like SetKey.
+func (b *BTD) UnmarshalKey(data []byte) error {
+	d := &decoder{buf: data}
+	b.decodeHeader(d, "key")
+	n, t := d.uint32(), d.uint32()
+	if d.err == nil && (t < 1 || n < t || n > math.MaxInt32) {
+		return fmt.Errorf("invalid key threshold %d of %d", t, n)
+	}
+	if d.err == nil && uint64(t) > uint64(len(d.buf)/4) {
+		return fmt.Errorf("decoding key: %d commitments in %d bytes", t, len(d.buf))
+	}
+	commits := make([]kyber.Point, t)
+	for k := range commits {
+		commits[k] = b.suite.G1().Point()
+		d.unmarshaler("commitment", commits[k])
+	}
+	if err := d.finish(); err != nil {
+		return fmt.Errorf("decoding key: %w", err)
+	}
+	b.SetKey(share.NewPubPoly(b.suite.G1(), nil, commits), int(n))
+	return nil
+}
+
+// MarshalKeyShare encodes the key share sk of a committee member.
+func (b *BTD) MarshalKeyShare(sk *share.PriShare) ([]byte, error) {
+	e := &encoder{}
+	e.uint8(keyVersion)
+	e.suite(b.suite.Name())
+	e.uint32(sk.I)
+	e.marshaler(sk.V)
+	return e.buf, e.err
+}
+
+// UnmarshalKeyShare decodes a key share encoded with MarshalKeyShare. If the key of b is set, the share must match
+// the verification key of its index.
+func (b *BTD) UnmarshalKeyShare(data []byte) (*share.PriShare, error) {
+	d := &decoder{buf: data}
+	b.decodeHeader(d, "key share")
+	i := d.uint32()
+	sk := &share.PriShare{I: i, V: b.suite.G1().Scalar()}
+	d.unmarshaler("key share", sk.V)
+	if err := d.finish(); err != nil {
+		return nil, fmt.Errorf("decoding key share: %w", err)
+	}
+	if b.eg.Commits != nil {
+		if int64(sk.I) >= int64(b.N) {
+			return nil, fmt.Errorf("key share index %d out of range [0, %d-1]", sk.I, b.N)
+		}
+		if !b.suite.G1().Point().Mul(sk.V, nil).Equal(b.eg.VerificationKey(i)) {
+			return nil, fmt.Errorf("key share %d does not match the public key", sk.I)
+		}
+	}
+	return sk, nil
+}
+
+// MarshalDecShare encodes the decryption share d together with the suite of b.
+func (b *BTD) MarshalDecShare(d *elgamal.DecShare) ([]byte, error) {
+	data, err := d.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	e := &encoder{}
+	e.uint8(keyVersion)
+	e.suite(b.suite.Name())
+	e.buf = append(e.buf, data...)
+	return e.buf, e.err
+}
+
+// UnmarshalDecShare decodes a decryption share encoded with MarshalDecShare. The share is not verified, see
+// VerifyShare.
+func (b *BTD) UnmarshalDecShare(data []byte) (*elgamal.DecShare, error) {
+	d := &decoder{buf: data}
+	b.decodeHeader(d, "decryption share")
+	if d.err != nil {
+		return nil, fmt.Errorf("decoding decryption share: %w", d.err)
+	}
+	return b.eg.UnmarshalDecShare(d.buf)
+}
+
+// PublicKey returns the public key of the committee, or nil if the key has not been set.
+func (b *BTD) PublicKey() kyber.Point {
+	return b.eg.PK
+}
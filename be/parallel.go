@@ -0,0 +1,58 @@
+package be
+
+import (
+	"btd/elgamal"
+	"context"
+	"go.dedis.ch/kyber/v4"
+	"runtime"
+	"sync"
+)
+
+// BatchCombineParallel is BatchCombine with the per-ciphertext decryptions spread over a pool of workers goroutines,
+// or one per CPU if workers < 1. The decryption shares are combined once up front; every worker then takes the next
+// undecrypted index of the batch. If ctx is canceled or a decryption fails, the workers stop taking new indices and
+// the first error is returned. The messages are the same as those of BatchCombine.
+func (b *BTD) BatchCombineParallel(
+	ctx context.Context, cts []CT, d []*elgamal.DecShare, verify bool, workers int,
+) ([]kyber.Point, error) {
+	K, err := b.combineKey(cts, d, verify)
+	if err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	idxs := make(chan int)
+	ms := make([]kyber.Point, len(cts))
+	wg := sync.WaitGroup{}
+	for w := 0; w < min(workers, len(cts)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range idxs {
+				m, err := b.unpadOne(cts, K, idx)
+				if err != nil {
+					cancel(err)
+					return
+				}
+				ms[idx] = m
+			}
+		}()
+	}
+feed:
+	for idx := range cts {
+		select {
+		case idxs <- idx:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(idxs)
+	wg.Wait()
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
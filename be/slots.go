@@ -0,0 +1,46 @@
+package be
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDuplicateIndex is returned for a batch with two ciphertexts on the same index. The punctured evaluations of
+// such a batch do not cancel, so it cannot be decrypted.
+var ErrDuplicateIndex = errors.New("duplicate ciphertext index in batch")
+
+// checkIndices checks that no two ciphertexts of the batch cts share an index. A batch may use any subset of the
+// B indices of the CRS, in any order.
+func checkIndices(cts []CT) error {
+	seen := make(map[int]struct{}, len(cts))
+	for _, ct := range cts {
+		if _, ok := seen[ct.i]; ok {
+			return fmt.Errorf("ciphertext on index %d: %w", ct.i, ErrDuplicateIndex)
+		}
+		seen[ct.i] = struct{}{}
+	}
+	return nil
+}
+
+// EmptySlots returns the indices in [0, B-1] that no ciphertext of the batch cts uses, in increasing order, so the
+// block builder can publish the actual composition of a batch that did not fill all slots. It fails if an index is
+// out of range or used twice.
+func (b *BTD) EmptySlots(cts []CT) ([]int, error) {
+	used := make([]bool, b.B)
+	for _, ct := range cts {
+		if ct.i < 0 || ct.i >= b.B {
+			return nil, fmt.Errorf("ciphertext index %d out of range [0, %d-1]", ct.i, b.B)
+		}
+		if used[ct.i] {
+			return nil, fmt.Errorf("ciphertext on index %d: %w", ct.i, ErrDuplicateIndex)
+		}
+		used[ct.i] = true
+	}
+	var empty []int
+	for i, u := range used {
+		if !u {
+			empty = append(empty, i)
+		}
+	}
+	return empty, nil
+}
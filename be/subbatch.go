@@ -0,0 +1,86 @@
+package be
+
+import (
+	"btd/elgamal"
+	"fmt"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/share"
+	"math"
+)
+
+// Partition splits a batch into alpha consecutive sub-batches of almost equal size, which are decrypted
+// independently. Every committee member sends one decryption share per sub-batch, so alpha trades communication
+// (alpha shares per member) for computation (the combiner evaluates the PRF on about B^2/alpha points instead of
+// B^2). The paper uses alpha = sqrt(B) (OPT-1) and alpha = 2*sqrt(B) (OPT-2), see SqrtAlpha.
+type Partition [][]CT
+
+// SqrtAlpha returns the number of sub-batches floor(factor*sqrt(B)) for a batch of B ciphertexts, but at least 1.
+func SqrtAlpha(B int, factor float64) int {
+	return max(1, int(math.Floor(factor*math.Sqrt(float64(B)))))
+}
+
+// NewPartition splits cts into alpha sub-batches. Sub-batch j holds cts[round(j*l):round((j+1)*l)] with
+// l = len(cts)/alpha, so the sizes of the sub-batches differ by at most one. All members and the combiner must
+// partition a batch with the same alpha.
+func NewPartition(cts []CT, alpha int) (Partition, error) {
+	if alpha < 1 || alpha > len(cts) {
+		return nil, fmt.Errorf("cannot split %d ciphertexts into %d sub-batches", len(cts), alpha)
+	}
+	subBatchLen := float64(len(cts)) / float64(alpha)
+	p := make(Partition, alpha)
+	for j := range p {
+		start := int(math.Round(float64(j) * subBatchLen))
+		end := int(math.Round(float64(j+1) * subBatchLen))
+		if j == alpha-1 {
+			end = len(cts)
+		}
+		p[j] = cts[start:end]
+	}
+	return p, nil
+}
+
+// Len returns the number of ciphertexts in all sub-batches.
+func (p Partition) Len() int {
+	n := 0
+	for _, sub := range p {
+		n += len(sub)
+	}
+	return n
+}
+
+// BatchDecSub computes the decryption shares of all sub-batches of p with the secret share sk. The j-th returned
+// share is the share of p[j].
+func (b *BTD) BatchDecSub(p Partition, sk *share.PriShare, verify bool) ([]*elgamal.DecShare, error) {
+	ds := make([]*elgamal.DecShare, len(p))
+	for j, sub := range p {
+		d, err := b.BatchDec(sub, sk, verify)
+		if err != nil {
+			return nil, fmt.Errorf("sub-batch %d: %w", j, err)
+		}
+		ds[j] = d
+	}
+	return ds, nil
+}
+
+// BatchCombineSub combines the decryption shares of the sub-batches of p, where d[k] are the shares of the k-th
+// committee member as returned by BatchDecSub. The messages are returned in the order of the partitioned batch.
+func (b *BTD) BatchCombineSub(p Partition, d [][]*elgamal.DecShare, verify bool) ([]kyber.Point, error) {
+	for k := range d {
+		if len(d[k]) != len(p) {
+			return nil, fmt.Errorf("got %d decryption shares from member %d for %d sub-batches", len(d[k]), k, len(p))
+		}
+	}
+	ms := make([]kyber.Point, 0, p.Len())
+	shares := make([]*elgamal.DecShare, len(d))
+	for j, sub := range p {
+		for k := range d {
+			shares[k] = d[k][j]
+		}
+		m, err := b.BatchCombine(sub, shares, verify)
+		if err != nil {
+			return nil, fmt.Errorf("sub-batch %d: %w", j, err)
+		}
+		ms = append(ms, m...)
+	}
+	return ms, nil
+}
@@ -0,0 +1,77 @@
+package be
+
+import (
+	"btd/curves"
+	"errors"
+	"go.dedis.ch/kyber/v4"
+)
+
+// VerifyBatch verifies the proofs of all ciphertexts in cts at once. For every ciphertext j, the three verification
+// equations of VerifyCT are combined with random weights rj, sj, tj into
+//
+//	rj*(uHat*g - Ap - h*A) + sj*(uHat*pk + kHat*g - Bp - h*B) + tj*(kHat*G1xi - yp - h*kp) = 0
+//
+// and the sum over all ciphertexts is checked with two multi-scalar multiplications: one over the proof commitments
+// with the short weights, and one over the remaining points. If the combined check fails, the batch is split in
+// halves until the invalid proofs are found. The returned error joins the *ProofError of every invalid ciphertext.
+func (b *BTD) VerifyBatch(cts []CT) error {
+	var errs []error
+	valid := make([]CT, 0, len(cts))
+	hs := make([]kyber.Scalar, 0, len(cts))
+	for _, ct := range cts {
+		h, err := b.challenge(ct)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		valid = append(valid, ct)
+		hs = append(hs, h)
+	}
+	// The same weights can be used for all sub-batches, as every combined check on its own is sound.
+	w := curves.RandomWeights(b.suite.G1(), b.suite.RandomStream(), 3*len(valid))
+	errs = append(errs, b.verifyBatch(valid, hs, w)...)
+	return errors.Join(errs...)
+}
+
+func (b *BTD) verifyBatch(cts []CT, hs, w []kyber.Scalar) []error {
+	if len(cts) == 0 || b.checkCombined(cts, hs, w) {
+		return nil
+	}
+	if len(cts) == 1 {
+		if err := b.VerifyCT(cts[0]); err != nil {
+			return []error{err}
+		}
+		return nil
+	}
+	m := len(cts) / 2
+	return append(b.verifyBatch(cts[:m], hs[:m], w[:3*m]), b.verifyBatch(cts[m:], hs[m:], w[3*m:])...)
+}
+
+// checkCombined checks the combination of the verification equations of cts with the weights w.
+func (b *BTD) checkCombined(cts []CT, hs, w []kyber.Scalar) bool {
+	g := b.suite.G1()
+	n := len(cts)
+	// sum_j rj*Ap + sj*Bp + tj*yp
+	commits := make([]kyber.Point, 0, 3*n)
+	// (sum_j rj*uHat + sj*kHat)*g + (sum_j sj*uHat)*pk + sum_j tj*kHat*G1xi - rj*h*A - sj*h*B - tj*h*kp
+	scalars := make([]kyber.Scalar, 2, 2+4*n)
+	points := make([]kyber.Point, 2, 2+4*n)
+	scalars[0], scalars[1] = g.Scalar().Zero(), g.Scalar().Zero()
+	points[0], points[1] = g.Point().Base(), b.eg.PK
+	tmp := g.Scalar()
+	for j, ct := range cts {
+		r, s, t := w[3*j], w[3*j+1], w[3*j+2]
+		commits = append(commits, ct.pi.Ap, ct.pi.Bp, ct.pi.yp)
+		scalars[0].Add(scalars[0], tmp.Mul(r, ct.pi.uHat))
+		scalars[0].Add(scalars[0], tmp.Mul(s, ct.pi.kHat))
+		scalars[1].Add(scalars[1], tmp.Mul(s, ct.pi.uHat))
+		rh := g.Scalar().Mul(r, hs[j])
+		sh := g.Scalar().Mul(s, hs[j])
+		th := g.Scalar().Mul(t, hs[j])
+		scalars = append(scalars, g.Scalar().Mul(t, ct.pi.kHat), rh.Neg(rh), sh.Neg(sh), th.Neg(th))
+		points = append(points, b.prf.G1xi[ct.i], ct.c.A, ct.c.B, ct.kp)
+	}
+	lhs := curves.MultiScalarMul(g, scalars, points)
+	rhs := curves.MultiScalarMul(g, w[:3*n], commits)
+	return lhs.Equal(rhs)
+}
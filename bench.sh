@@ -12,9 +12,12 @@ benchmarks=(
     "BatchCombine512Slow:2x"
     "BatchCombine512Fast:10x"
     "BatchCombineParSqrt:50x"
+    "Roles:10x"
 )
 
 benchdir="bench"
+# Comma-separated suites to benchmark, see curves.Names. Set SUITE="" to benchmark all registered suites.
+suite="${SUITE-bls12381-kilic}"
 
 mkdir -p "$benchdir"
 
@@ -22,7 +25,7 @@ for entry in "${benchmarks[@]}"; do
     IFS=":" read -r bench benchtime <<< "$entry"
 
     # Run the benchmark and save the output to a file
-    go test -bench="$bench" -benchtime="$benchtime" > "${benchdir}/bench-${bench}.txt"
+    go test -bench="$bench" -benchtime="$benchtime" -suite="$suite" > "${benchdir}/bench-${bench}.txt"
     echo "Saved results of $bench to $benchdir"
 done
 
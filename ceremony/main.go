@@ -0,0 +1,132 @@
+// Command ceremony runs the multi-party generation of the CRS by passing a ceremony file between operators:
+//
+//	ceremony init -B 512 -suite bls12381-kilic -out c0.bin
+//	ceremony contribute -in c0.bin -out c1.bin
+//	ceremony verify -in c1.bin
+//	ceremony export -in c1.bin -out crs.bin
+//
+// Ceremony files carry the name of their pairing suite, so -suite is only needed for init.
+package main
+
+import (
+	"btd/curves"
+	"btd/prf"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	in := fs.String("in", "", "ceremony file to read")
+	out := fs.String("out", "", "file to write")
+	B := fs.Int("B", 0, "domain size of the CRS (init only)")
+	name := fs.String("suite", "", "pairing suite, one of "+strings.Join(curves.Names(), ", ")+
+		" (default "+curves.Default+" for init, the suite of the input file otherwise)")
+	fs.Parse(os.Args[2:])
+
+	// A nil suite makes the prf package read the suite from the ceremony file.
+	var suite curves.Suite
+	var err error
+	if *name == "" && os.Args[1] == "init" {
+		*name = curves.Default
+	}
+	if *name != "" {
+		if suite, err = curves.Lookup(*name); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	switch os.Args[1] {
+	case "init":
+		err = initCeremony(suite, *B, *out)
+	case "contribute":
+		err = contribute(suite, *in, *out)
+	case "verify":
+		err = verify(suite, *in)
+	case "export":
+		err = export(suite, *in, *out)
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: ceremony init|contribute|verify|export [flags]")
+	os.Exit(2)
+}
+
+func initCeremony(suite curves.Suite, B int, out string) error {
+	if B < 1 || out == "" {
+		return fmt.Errorf("init requires -B and -out")
+	}
+	// The initial contribution makes the ceremony verifiable from the start.
+	c := prf.NewCeremony(suite, B)
+	if err := c.Contribute(); err != nil {
+		return err
+	}
+	if err := c.Save(out); err != nil {
+		return err
+	}
+	fmt.Printf("Initialized ceremony for B=%d with 1 contribution\n", B)
+	return nil
+}
+
+func contribute(suite curves.Suite, in, out string) error {
+	if in == "" || out == "" {
+		return fmt.Errorf("contribute requires -in and -out")
+	}
+	c, err := prf.LoadCeremony(suite, in)
+	if err != nil {
+		return err
+	}
+	// Never build on top of a ceremony that does not verify.
+	if err := c.Verify(); err != nil {
+		return fmt.Errorf("refusing to contribute to invalid ceremony: %w", err)
+	}
+	if err := c.Contribute(); err != nil {
+		return err
+	}
+	if err := c.Save(out); err != nil {
+		return err
+	}
+	fmt.Printf("Added contribution %d\n", len(c.Contributions))
+	return nil
+}
+
+func verify(suite curves.Suite, in string) error {
+	if in == "" {
+		return fmt.Errorf("verify requires -in")
+	}
+	c, err := prf.LoadCeremony(suite, in)
+	if err != nil {
+		return err
+	}
+	if err := c.Verify(); err != nil {
+		return err
+	}
+	fmt.Printf("Ceremony with %d contributions verified\n", len(c.Contributions))
+	return nil
+}
+
+func export(suite curves.Suite, in, out string) error {
+	if in == "" || out == "" {
+		return fmt.Errorf("export requires -in and -out")
+	}
+	c, err := prf.LoadCeremony(suite, in)
+	if err != nil {
+		return err
+	}
+	if err := c.Verify(); err != nil {
+		return err
+	}
+	return c.CRS().SaveCRS(out)
+}
@@ -1,27 +1,98 @@
 package curves
 
 import (
+	"fmt"
 	"go.dedis.ch/kyber/v4"
 	"go.dedis.ch/kyber/v4/pairing"
+	"go.dedis.ch/kyber/v4/pairing/bls12381/circl"
+	"go.dedis.ch/kyber/v4/pairing/bls12381/kilic"
+	"go.dedis.ch/kyber/v4/pairing/bn254"
+	"go.dedis.ch/kyber/v4/pairing/bn256"
 )
 
+// Suite is a pairing suite as seen by the scheme. Its G1 is the group of the punctured keys, the ElGamal ciphertexts
+// and the proofs, and its G2 is the group of the CRS elements g2^zi and g2^{zi/xj}. Which source group of the
+// underlying pairing plays which role is given by Roles.
 type Suite interface {
 	pairing.Suite
 	GTBase() kyber.Point
 	PickGT() kyber.Point
+	// Name identifies the underlying pairing suite and the roles of its groups. It is used to tag serialized objects.
+	Name() string
+	// MultiPair computes sum_k e(p1s[k], p2s[k]).
+	MultiPair(p1s, p2s []kyber.Point) kyber.Point
+	// Roles returns the assignment of the source groups of the pairing to G1 and G2 of the suite.
+	Roles() Roles
 }
 
 type suite struct {
 	pairing.Suite
-	gtBase kyber.Point
+	gtBase    kyber.Point
+	name      string
+	multiPair multiPairer
+	roles     Roles
 }
 
+// NewSuite wraps the pairing suite s with the default roles CTInG1.
 func NewSuite(s pairing.Suite) Suite {
 	gtBase := s.Pair(s.G1().Point().Base(), s.G2().Point().Base())
+	var multiPair multiPairer
+	if ks, ok := s.(*kilic.Suite); ok {
+		multiPair = kilicMultiPairer(ks)
+	}
 	return &suite{
-		Suite:  s,
-		gtBase: gtBase,
+		Suite:     s,
+		gtBase:    gtBase,
+		name:      suiteName(s),
+		multiPair: multiPair,
+	}
+}
+
+func suiteName(s pairing.Suite) string {
+	switch s.(type) {
+	case *kilic.Suite:
+		return "bls12381-kilic"
+	case circl.Suite, *circl.Suite:
+		return "bls12381-circl"
+	case *bn254.Suite:
+		return "bn254"
+	case *bn256.Suite:
+		return "bn256"
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+// G1 returns the group of the punctured keys and ciphertexts.
+func (s *suite) G1() kyber.Group {
+	if s.roles == CRSInG1 {
+		return s.Suite.G2()
+	}
+	return s.Suite.G1()
+}
+
+// G2 returns the group of the CRS elements.
+func (s *suite) G2() kyber.Group {
+	if s.roles == CRSInG1 {
+		return s.Suite.G1()
 	}
+	return s.Suite.G2()
+}
+
+// Pair computes the pairing of p1 in G1 and p2 in G2 on copies of the points. Some suites, such as kilic, normalize
+// the inputs of a pairing in place, which races with concurrent readers of shared points like the CRS.
+func (s *suite) Pair(p1, p2 kyber.Point) kyber.Point {
+	if s.roles == CRSInG1 {
+		p1, p2 = p2, p1
+	}
+	return s.Suite.Pair(p1.Clone(), p2.Clone())
+}
+
+// ValidatePairing checks e(p1, p2) = e(p3, p4) on copies of the points, see Pair.
+func (s *suite) ValidatePairing(p1, p2, p3, p4 kyber.Point) bool {
+	if s.roles == CRSInG1 {
+		p1, p2, p3, p4 = p2, p1, p4, p3
+	}
+	return s.Suite.ValidatePairing(p1.Clone(), p2.Clone(), p3.Clone(), p4.Clone())
 }
 
 func (s *suite) PickGT() kyber.Point {
@@ -32,3 +103,11 @@ func (s *suite) PickGT() kyber.Point {
 func (s *suite) GTBase() kyber.Point {
 	return s.gtBase.Clone()
 }
+
+func (s *suite) Name() string {
+	return s.name + s.roles.suffix()
+}
+
+func (s *suite) Roles() Roles {
+	return s.roles
+}
@@ -0,0 +1,117 @@
+package curves
+
+import (
+	"crypto/cipher"
+	"go.dedis.ch/kyber/v4"
+	"math/bits"
+)
+
+// WeightBytes is the length of the weights returned by RandomWeights. A randomized check that combines equations
+// with such weights accepts a false equation with probability at most 2^-128.
+const WeightBytes = 16
+
+// RandomWeights returns n random scalars of g of WeightBytes bytes each, for combining equations in a batch
+// verification. Short weights keep the multi-scalar multiplications of the combined check cheap.
+func RandomWeights(g kyber.Group, rng cipher.Stream, n int) []kyber.Scalar {
+	buf := make([]byte, WeightBytes)
+	r := make([]kyber.Scalar, n)
+	for i := range r {
+		clear(buf)
+		rng.XORKeyStream(buf, buf)
+		r[i] = g.Scalar().SetBytes(buf)
+	}
+	return r
+}
+
+// MultiScalarMul computes sum_i scalars[i] * points[i] in g with the bucket method of Pippenger. For n points it
+// needs roughly (b/c) * (n + 2^c) point additions, where b is the bit length of the largest scalar and c ~ log2(n),
+// instead of the b doublings and b/2 additions per point of computing the products one by one. Short scalars, such as
+// the random weights of a batch verification, are therefore particularly cheap.
+func MultiScalarMul(g kyber.Group, scalars []kyber.Scalar, points []kyber.Point) kyber.Point {
+	if len(scalars) != len(points) {
+		panic("number of scalars and points differ")
+	}
+	res := g.Point().Null()
+	if len(points) == 0 {
+		return res
+	}
+	// Scalars in big-endian byte order, so that bit k of scalar i is (bs[i][len-1-k/8] >> (k%8)) & 1.
+	littleEndian := isLittleEndian(g)
+	bs := make([][]byte, len(scalars))
+	maxBits := 0
+	for i, s := range scalars {
+		b, err := s.MarshalBinary()
+		if err != nil {
+			panic(err)
+		}
+		if littleEndian {
+			for l, r := 0, len(b)-1; l < r; l, r = l+1, r-1 {
+				b[l], b[r] = b[r], b[l]
+			}
+		}
+		bs[i] = b
+		maxBits = max(maxBits, bitLen(b))
+	}
+	c := max(1, min(16, bits.Len(uint(len(points)))-2))
+	windows := (maxBits + c - 1) / c
+	buckets := make([]kyber.Point, 1<<c)
+	for w := windows - 1; w >= 0; w-- {
+		for k := 0; k < c; k++ {
+			res = res.Add(res, res)
+		}
+		clear(buckets)
+		for i, p := range points {
+			d := digit(bs[i], w*c, c)
+			if d == 0 {
+				continue
+			}
+			if buckets[d] == nil {
+				buckets[d] = p.Clone()
+			} else {
+				buckets[d] = buckets[d].Add(buckets[d], p)
+			}
+		}
+		// sum_d d * bucket[d] computed as a sum of running sums.
+		running := g.Point().Null()
+		sum := g.Point().Null()
+		for d := len(buckets) - 1; d > 0; d-- {
+			if buckets[d] != nil {
+				running = running.Add(running, buckets[d])
+			}
+			sum = sum.Add(sum, running)
+		}
+		res = res.Add(res, sum)
+	}
+	return res
+}
+
+func isLittleEndian(g kyber.Group) bool {
+	b, err := g.Scalar().SetInt64(1).MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	return b[0] == 1
+}
+
+// bitLen returns the bit length of the big-endian integer b.
+func bitLen(b []byte) int {
+	for i, v := range b {
+		if v != 0 {
+			return (len(b)-i-1)*8 + bits.Len8(v)
+		}
+	}
+	return 0
+}
+
+// digit returns the c bits of the big-endian integer b starting at bit position off.
+func digit(b []byte, off, c int) int {
+	d := 0
+	for k := c - 1; k >= 0; k-- {
+		pos := off + k
+		d <<= 1
+		if pos/8 < len(b) {
+			d |= int(b[len(b)-1-pos/8]>>(pos%8)) & 1
+		}
+	}
+	return d
+}
@@ -0,0 +1,34 @@
+package curves_test
+
+import (
+	"btd/curves"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v4"
+	"testing"
+)
+
+func TestMultiScalarMul(t *testing.T) {
+	for _, suite := range curves.All() {
+		for gi, g := range []kyber.Group{suite.G1(), suite.G2(), suite.GT()} {
+			for _, n := range []int{0, 1, 5, 40} {
+				scalars := make([]kyber.Scalar, n)
+				points := make([]kyber.Point, n)
+				want := g.Point().Null()
+				for i := 0; i < n; i++ {
+					scalars[i] = g.Scalar().Pick(suite.RandomStream())
+					if i == 1 {
+						scalars[i] = g.Scalar().SetInt64(3)
+					}
+					if gi == 2 {
+						points[i] = suite.PickGT()
+					} else {
+						points[i] = g.Point().Pick(suite.RandomStream())
+					}
+					want = want.Add(want, g.Point().Mul(scalars[i], points[i]))
+				}
+				got := curves.MultiScalarMul(g, scalars, points)
+				require.True(t, want.Equal(got), "%s %s n=%d", suite.Name(), g, n)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package curves
+
+import (
+	bls12381 "github.com/kilic/bls12-381"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/pairing/bls12381/kilic"
+)
+
+// multiPairer computes sum_k e(p1s[k], p2s[k]) with a single Miller loop over all pairs and one final
+// exponentiation.
+type multiPairer func(p1s, p2s []kyber.Point) kyber.Point
+
+// MultiPair computes sum_k e(p1s[k], p2s[k]). Suites with a multi-Miller loop share the final exponentiation between
+// all pairings, which roughly halves the cost per pairing. On the other suites, the pairings are computed one by one.
+func (s *suite) MultiPair(p1s, p2s []kyber.Point) kyber.Point {
+	if len(p1s) != len(p2s) {
+		panic("number of G1 and G2 points differ")
+	}
+	if s.roles == CRSInG1 {
+		p1s, p2s = p2s, p1s
+	}
+	if s.multiPair != nil {
+		return s.multiPair(p1s, p2s)
+	}
+	sum := s.GT().Point().Null()
+	for k := range p1s {
+		sum = sum.Add(sum, s.Suite.Pair(p1s[k].Clone(), p2s[k].Clone()))
+	}
+	return sum
+}
+
+// kilicMultiPairer returns a multi-pairing for the kilic suite. The kyber wrappers of the kilic points do not expose
+// the underlying points, so the points are passed to the engine of kilic through their encodings.
+func kilicMultiPairer(s *kilic.Suite) multiPairer {
+	return func(p1s, p2s []kyber.Point) kyber.Point {
+		e := bls12381.NewEngine()
+		for k := range p1s {
+			g1, err := decodeKilic(p1s[k], e.G1.FromCompressed)
+			if err != nil {
+				panic(err)
+			}
+			g2, err := decodeKilic(p2s[k], e.G2.FromCompressed)
+			if err != nil {
+				panic(err)
+			}
+			e.AddPair(g1, g2)
+		}
+		res := s.GT().Point()
+		if err := res.UnmarshalBinary(bls12381.NewGT().ToBytes(e.Result())); err != nil {
+			panic(err)
+		}
+		return res
+	}
+}
+
+// decodeKilic decodes the kilic point underlying the kyber point p with fromCompressed.
+func decodeKilic[T any](p kyber.Point, fromCompressed func([]byte) (T, error)) (T, error) {
+	buf, err := p.MarshalBinary()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return fromCompressed(buf)
+}
@@ -0,0 +1,30 @@
+package curves_test
+
+import (
+	"btd/curves"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v4"
+	"testing"
+)
+
+func TestMultiPair(t *testing.T) {
+	for _, suite := range curves.All() {
+		for _, n := range []int{0, 1, 6} {
+			p1s := make([]kyber.Point, n)
+			p2s := make([]kyber.Point, n)
+			want := suite.GT().Point().Null()
+			for k := 0; k < n; k++ {
+				p1s[k] = suite.G1().Point().Pick(suite.RandomStream())
+				p2s[k] = suite.G2().Point().Pick(suite.RandomStream())
+				if k == 1 {
+					p1s[k] = suite.G1().Point().Null()
+				}
+				want = want.Add(want, suite.Pair(p1s[k], p2s[k]))
+			}
+			// The result can be used like any other GT element.
+			got := suite.MultiPair(p1s, p2s)
+			require.True(t, want.Equal(got), "%s n=%d", suite.Name(), n)
+			require.True(t, want.Add(want, want).Equal(got.Add(got, got)), "%s n=%d", suite.Name(), n)
+		}
+	}
+}
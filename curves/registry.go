@@ -0,0 +1,81 @@
+package curves
+
+import (
+	"fmt"
+	"go.dedis.ch/kyber/v4/pairing/bls12381/circl"
+	"go.dedis.ch/kyber/v4/pairing/bls12381/kilic"
+	"go.dedis.ch/kyber/v4/pairing/bn254"
+	"go.dedis.ch/kyber/v4/pairing/bn256"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Default is the name of the suite used when none is selected. It is the suite of the benchmarks in the paper.
+const Default = "bls12381-kilic"
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]func() Suite{
+		"bls12381-kilic": func() Suite { return NewSuite(kilic.NewBLS12381Suite()) },
+		"bls12381-circl": func() Suite { return NewSuite(circl.NewSuite()) },
+		"bn254":          func() Suite { return NewSuite(bn254.NewSuite()) },
+		"bn256":          func() Suite { return NewSuite(bn256.NewSuite()) },
+	}
+)
+
+// Register makes the suite returned by newSuite available under name, which must be the name of that suite. It
+// panics if name is already registered or contains the separator of the roles.
+func Register(name string, newSuite func() Suite) {
+	if strings.Contains(name, rolesSep) {
+		panic("curves: invalid suite name " + name)
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		panic("curves: suite " + name + " registered twice")
+	}
+	registry[name] = newSuite
+}
+
+// Lookup returns a new instance of the suite registered under name. The name may select non-default roles with a
+// suffix, as in "bn254:crs-g1", see WithRoles. Serialized CRSs, ceremonies and ciphertexts carry the name of their
+// suite, so Lookup finds the suite to decode them in.
+func Lookup(name string) (Suite, error) {
+	curve, roles, err := splitRoles(name)
+	if err != nil {
+		return nil, err
+	}
+	registryMu.RLock()
+	newSuite, ok := registry[curve]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown suite %q, registered suites: %s", curve, strings.Join(Names(), ", "))
+	}
+	if roles == CTInG1 {
+		return newSuite(), nil
+	}
+	return WithRoles(newSuite(), roles), nil
+}
+
+// Names returns the names of all registered suites in lexical order. The suites have the default roles.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// All returns an instance of every registered suite, in the order of Names.
+func All() []Suite {
+	names := Names()
+	suites := make([]Suite, len(names))
+	for i, name := range names {
+		suites[i], _ = Lookup(name)
+	}
+	return suites
+}
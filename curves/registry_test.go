@@ -0,0 +1,54 @@
+package curves_test
+
+import (
+	"btd/curves"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/pairing/bn256"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	require.Equal(t, []string{"bls12381-circl", "bls12381-kilic", "bn254", "bn256"}, curves.Names())
+	for _, name := range curves.Names() {
+		suite, err := curves.Lookup(name)
+		require.NoError(t, err)
+		require.Equal(t, name, suite.Name())
+	}
+	_, err := curves.Lookup("p256")
+	require.Error(t, err)
+	require.Panics(t, func() {
+		curves.Register("bn256", func() curves.Suite { return curves.NewSuite(bn256.NewSuite()) })
+	})
+}
+
+func TestRoles(t *testing.T) {
+	for _, suite := range curves.All() {
+		swapped := curves.WithRoles(suite, curves.CRSInG1)
+		require.Equal(t, curves.CTInG1, suite.Roles())
+		require.Equal(t, curves.CRSInG1, swapped.Roles())
+		require.Equal(t, suite.Name()+":crs-g1", swapped.Name())
+		require.Equal(t, suite.G2().PointLen(), swapped.G1().PointLen())
+		require.Equal(t, suite.G1().PointLen(), swapped.G2().PointLen())
+		require.True(t, suite.GTBase().Equal(swapped.GTBase()))
+		require.Equal(t, suite.Name(), curves.WithRoles(swapped, curves.CTInG1).Name())
+
+		looked, err := curves.Lookup(swapped.Name())
+		require.NoError(t, err)
+		require.Equal(t, swapped.Name(), looked.Name())
+
+		// The pairing of the swapped suite takes its arguments in the swapped groups and is bilinear.
+		a := swapped.G1().Scalar().Pick(swapped.RandomStream())
+		b := swapped.G1().Scalar().Pick(swapped.RandomStream())
+		P := swapped.G1().Point().Mul(a, nil)
+		Q := swapped.G2().Point().Mul(b, nil)
+		want := swapped.GT().Point().Mul(swapped.GT().Scalar().Mul(a, b), swapped.GTBase())
+		require.True(t, want.Equal(swapped.Pair(P, Q)), swapped.Name())
+		require.True(t, want.Equal(swapped.MultiPair([]kyber.Point{P}, []kyber.Point{Q})), swapped.Name())
+		bP, bQ := swapped.G1().Point().Mul(b, P), swapped.G2().Point().Mul(b, Q)
+		require.True(t, swapped.ValidatePairing(bP, Q, P, bQ), swapped.Name())
+		require.False(t, swapped.ValidatePairing(P, Q, P, bQ), swapped.Name())
+	}
+	_, err := curves.Lookup("bn254:ct-g2")
+	require.Error(t, err)
+}
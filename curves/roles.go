@@ -0,0 +1,78 @@
+package curves
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Roles assigns the two source groups of a pairing to the roles of the scheme. Every ciphertext carries a punctured
+// key, an ElGamal ciphertext and a proof of six elements in G1 of the suite, while the CRS holds B elements in G1 and
+// B^2 elements in G2 of the suite. The smaller and faster source group thus either shrinks the ciphertexts and speeds
+// up encryption and partial decryption, or shrinks the CRS and speeds up its setup and verification.
+type Roles uint8
+
+const (
+	// CTInG1 keeps the groups of the pairing: ciphertexts live in the source group G1 and the CRS in G2. This is
+	// the default, as G1 is the smaller and faster group on all registered curves.
+	CTInG1 Roles = iota
+	// CRSInG1 swaps the groups of the pairing: ciphertexts live in the source group G2 and the CRS in G1.
+	CRSInG1
+)
+
+// rolesSep separates the curve from the roles in the name of a suite with non-default roles.
+const rolesSep = ":"
+
+func (r Roles) String() string {
+	switch r {
+	case CTInG1:
+		return "ct-g1"
+	case CRSInG1:
+		return "crs-g1"
+	}
+	return fmt.Sprintf("Roles(%d)", r)
+}
+
+// suffix returns the suffix of the name of a suite with roles r. Suites with the default roles keep the name of
+// their curve, so that existing artifacts remain valid.
+func (r Roles) suffix() string {
+	if r == CTInG1 {
+		return ""
+	}
+	return rolesSep + r.String()
+}
+
+// ParseRoles returns the roles named s, as returned by Roles.String.
+func ParseRoles(s string) (Roles, error) {
+	for _, r := range []Roles{CTInG1, CRSInG1} {
+		if s == r.String() {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown roles %q, expected %s or %s", s, CTInG1, CRSInG1)
+}
+
+// WithRoles returns the suite s with the groups of its pairing assigned to the roles r. The returned suite is
+// named after the curve of s and r, e.g., "bn254:crs-g1", and can be found with Lookup under that name. Artifacts
+// of suites with different roles are incompatible. It panics if s was not created by NewSuite.
+func WithRoles(s Suite, r Roles) Suite {
+	base, ok := s.(*suite)
+	if !ok {
+		panic(fmt.Sprintf("curves: cannot assign roles to %T", s))
+	}
+	if r != CTInG1 && r != CRSInG1 {
+		panic(fmt.Sprintf("curves: invalid roles %d", r))
+	}
+	withRoles := *base
+	withRoles.roles = r
+	return &withRoles
+}
+
+// splitRoles splits the name of a suite into the name of its curve and its roles.
+func splitRoles(name string) (string, Roles, error) {
+	curve, roles, ok := strings.Cut(name, rolesSep)
+	if !ok {
+		return name, CTInG1, nil
+	}
+	r, err := ParseRoles(roles)
+	return curve, r, err
+}
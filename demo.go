@@ -0,0 +1,165 @@
+package main
+
+import (
+	"btd/be"
+	"btd/curves"
+	"btd/elgamal"
+	"flag"
+	"fmt"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/share"
+	"time"
+)
+
+func testPairing(suite curves.Suite, N int) {
+	g1 := make([]kyber.Point, N)
+	g2 := make([]kyber.Point, N)
+	gt := make([]kyber.Point, N)
+	for i := 0; i < N; i++ {
+		g1[i] = suite.G1().Point().Pick(suite.RandomStream())
+		g2[i] = suite.G2().Point().Pick(suite.RandomStream())
+	}
+	fmt.Println("Start")
+	start := time.Now()
+	for j := 0; j < N; j++ {
+		gt[j] = suite.Pair(g1[j], g2[j])
+	}
+	elapsed := time.Since(start)
+	fmt.Printf("%s: Elapsed time for %d pairings: %s\n", suite.Name(), N, elapsed)
+}
+
+// demo runs the whole scheme in-process with B=8, n=10 and t=5. The mode selects the decryption: naive decrypts the
+// whole batch at once, opt with the outdated log(B) optimization, sqrt with sqrt(B) sub-batches and sqrtlog with the
+// log(B) optimization within every sqrt(B) sub-batch. The mode pairing times 1000 pairings instead.
+func demo(args []string) error {
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	name := suiteFlag(fs)
+	mode := fs.String("mode", "sqrt", "decryption to run, one of naive, opt, sqrt, sqrtlog or pairing")
+	fs.Parse(args)
+	suite, err := curves.Lookup(*name)
+	if err != nil {
+		return err
+	}
+	modes := map[string]func(*be.BTD, []be.CT, []*share.PriShare, kyber.Point){
+		"naive":   testNaive,
+		"opt":     testOpt,
+		"sqrt":    testSqrt,
+		"sqrtlog": testOptSqrt,
+	}
+	if *mode == "pairing" {
+		testPairing(suite, 1000)
+		return nil
+	}
+	decrypt, ok := modes[*mode]
+	if !ok {
+		return fmt.Errorf("unknown demo mode %q", *mode)
+	}
+	B := 8
+	n := 10
+	t := 5
+	btd := be.NewBTD(suite, B)
+	sks, pk := btd.KeyGen(n, t)
+	fmt.Println("Setup succeeded")
+	m := suite.PickGT()
+	cts := make([]be.CT, B)
+	for i := 0; i < B; i++ {
+		ct, err := btd.Enc(pk, i, m, nil)
+		if err != nil {
+			return err
+		}
+		cts[i] = ct
+	}
+	fmt.Println("Encryption succeeded")
+	decrypt(btd, cts, sks, m)
+	return nil
+}
+
+func checkMessages(ms []kyber.Point, m kyber.Point) {
+	for i, mi := range ms {
+		if !mi.Equal(m) {
+			panic(fmt.Sprintf("decryption failed on index %d", i))
+		}
+	}
+}
+
+func testNaive(btd *be.BTD, cts []be.CT, sks []*share.PriShare, m kyber.Point) {
+	d := make([]*elgamal.DecShare, btd.T)
+	var err error
+	for i := 0; i < btd.T; i++ {
+		d[i], err = btd.BatchDec(cts, sks[i], true)
+		if err != nil {
+			panic(err)
+		}
+	}
+	ms, err := btd.BatchCombine(cts, d, false)
+	if err != nil {
+		panic(err)
+	}
+	checkMessages(ms, m)
+	fmt.Println("Decryption succeeded")
+	fmt.Println("Decrypted messages:", len(ms))
+}
+
+func testOpt(btd *be.BTD, cts []be.CT, sks []*share.PriShare, m kyber.Point) {
+	ds := make([][]*elgamal.DecShare, btd.T)
+	var err error
+	for i := 0; i < btd.T; i++ {
+		ds[i], err = btd.BatchDecOpt(cts, sks[i], true)
+		if err != nil {
+			panic(err)
+		}
+	}
+	ms, err := btd.BatchCombineOpt(cts, ds, false)
+	if err != nil {
+		panic(err)
+	}
+	checkMessages(ms, m)
+	fmt.Println("Optimized Decryption succeeded")
+	fmt.Println("Decrypted messages:", len(ms))
+}
+
+func testSqrt(btd *be.BTD, cts []be.CT, sks []*share.PriShare, m kyber.Point) {
+	p, err := be.NewPartition(cts, be.SqrtAlpha(btd.B, 1))
+	if err != nil {
+		panic(err)
+	}
+	ds := make([][]*elgamal.DecShare, btd.T)
+	for j := 0; j < btd.T; j++ {
+		ds[j], err = btd.BatchDecSub(p, sks[j], true)
+		if err != nil {
+			panic(err)
+		}
+	}
+	ms, err := btd.BatchCombineSub(p, ds, false)
+	if err != nil {
+		panic(err)
+	}
+	checkMessages(ms, m)
+	fmt.Println("Decryption with sqrt(B) sub-batches succeeded")
+	fmt.Println("Decrypted messages:", len(ms))
+}
+
+func testOptSqrt(btd *be.BTD, cts []be.CT, sks []*share.PriShare, m kyber.Point) {
+	p, err := be.NewPartition(cts, be.SqrtAlpha(btd.B, 1))
+	if err != nil {
+		panic(err)
+	}
+	count := 0
+	for _, sub := range p {
+		ds := make([][]*elgamal.DecShare, btd.T)
+		for j := 0; j < btd.T; j++ {
+			ds[j], err = btd.BatchDecOpt(sub, sks[j], true)
+			if err != nil {
+				panic(err)
+			}
+		}
+		ms, err := btd.BatchCombineOpt(sub, ds, false)
+		if err != nil {
+			panic(err)
+		}
+		checkMessages(ms, m)
+		count += len(ms)
+	}
+	fmt.Println("Optimized Decryption with sqrt(B)*log(sqrt(B)) communication succeeded")
+	fmt.Println("Decrypted messages:", count)
+}
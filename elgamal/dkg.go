@@ -0,0 +1,270 @@
+package elgamal
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/share"
+)
+
+// DKG is the state of one participant in a joint-Feldman distributed key generation (Pedersen's DKG). Every
+// participant deals a random (t,n)-Shamir sharing and publishes Feldman commitments to its polynomial. Participants
+// complain about shares that do not match the commitments, and a dealer has to answer every complaint by revealing
+// the disputed share. Dealers that fail to do so are disqualified. The master secret key is the sum of the secrets
+// of all qualified dealers and is never known to anyone.
+//
+// Participants are numbered 0, ..., n-1, which are also the indices of their shares. Messages are exchanged by the
+// caller: a Deal and Complaints/Justifications are broadcast, whereas the shares returned by Deal must be sent to
+// their recipient over a private channel.
+type DKG struct {
+	gr         kyber.Group
+	index      int
+	n, t       int
+	poly       *share.PriPoly
+	commits    map[int]*share.PubPoly
+	shares     map[int]*share.PriShare
+	complaints map[int]map[int]bool // dealer -> unanswered complaints by participant
+	disq       map[int]bool
+}
+
+// Deal is the broadcast part of a dealing: the Feldman commitments to the dealer's polynomial.
+type Deal struct {
+	Dealer  int
+	Commits []kyber.Point
+}
+
+// Complaint is broadcast by a participant that received no share or an invalid share from a dealer.
+type Complaint struct {
+	Dealer     int
+	Complainer int
+}
+
+// Justification is the dealer's answer to a complaint: the disputed share, revealed to everyone.
+type Justification struct {
+	Dealer int
+	Share  *share.PriShare
+}
+
+// DistKeyShare is the result of the DKG for one participant: its own share of the master secret key and the
+// public commitments to the master polynomial.
+type DistKeyShare struct {
+	Share   *share.PriShare
+	Commits *share.PubPoly
+}
+
+// Public returns the master public key.
+func (d *DistKeyShare) Public() kyber.Point {
+	return d.Commits.Commit()
+}
+
+// NewDKG creates the state of participant index in a DKG of n participants with threshold t.
+func NewDKG(gr kyber.Group, rng cipher.Stream, index, n, t int) (*DKG, error) {
+	if index < 0 || index >= n {
+		return nil, fmt.Errorf("participant index %d out of range [0, %d-1]", index, n)
+	}
+	if t < 1 || t > n {
+		return nil, fmt.Errorf("invalid threshold %d for %d participants", t, n)
+	}
+	return &DKG{
+		gr:         gr,
+		index:      index,
+		n:          n,
+		t:          t,
+		poly:       share.NewPriPoly(gr, t, nil, rng),
+		commits:    make(map[int]*share.PubPoly),
+		shares:     make(map[int]*share.PriShare),
+		complaints: make(map[int]map[int]bool),
+		disq:       make(map[int]bool),
+	}, nil
+}
+
+// Deal returns the commitments to broadcast and the shares to send privately, where shares[j] is meant for
+// participant j. The participant's own share is processed directly.
+func (d *DKG) Deal() (*Deal, []*share.PriShare) {
+	_, commits := d.poly.Commit(nil).Info()
+	deal := &Deal{Dealer: d.index, Commits: commits}
+	shares := d.poly.Shares(d.n)
+	d.ProcessDeal(deal, shares[d.index])
+	return deal, shares
+}
+
+// ProcessDeal processes the deal of a dealer together with the share the dealer privately sent to this
+// participant. The share may be nil if it was never received. If the share does not match the commitments, the
+// returned complaint must be broadcast.
+func (d *DKG) ProcessDeal(deal *Deal, s *share.PriShare) (*Complaint, error) {
+	if deal == nil {
+		return nil, errors.New("missing deal")
+	}
+	if deal.Dealer < 0 || deal.Dealer >= d.n {
+		return nil, fmt.Errorf("dealer index %d out of range", deal.Dealer)
+	}
+	if _, ok := d.commits[deal.Dealer]; ok {
+		return nil, fmt.Errorf("duplicate deal from dealer %d", deal.Dealer)
+	}
+	if len(deal.Commits) != d.t {
+		// A malformed deal is visible to everyone, so the dealer is disqualified right away.
+		d.disq[deal.Dealer] = true
+		return nil, fmt.Errorf("dealer %d committed to %d coefficients instead of %d", deal.Dealer, len(deal.Commits), d.t)
+	}
+	for k, c := range deal.Commits {
+		if c == nil {
+			d.disq[deal.Dealer] = true
+			return nil, fmt.Errorf("dealer %d committed to no coefficient %d", deal.Dealer, k)
+		}
+	}
+	commits := share.NewPubPoly(d.gr, nil, deal.Commits)
+	d.commits[deal.Dealer] = commits
+	d.complaints[deal.Dealer] = make(map[int]bool)
+	if s == nil || s.V == nil || int(s.I) != d.index || !commits.Check(s) {
+		d.complaints[deal.Dealer][d.index] = true
+		return &Complaint{Dealer: deal.Dealer, Complainer: d.index}, nil
+	}
+	d.shares[deal.Dealer] = s
+	return nil, nil
+}
+
+// ProcessComplaint records a broadcast complaint. If this participant is the accused dealer, it returns the
+// justification to broadcast.
+func (d *DKG) ProcessComplaint(c *Complaint) (*Justification, error) {
+	if c == nil {
+		return nil, errors.New("missing complaint")
+	}
+	if _, ok := d.commits[c.Dealer]; !ok {
+		return nil, fmt.Errorf("complaint about dealer %d without deal", c.Dealer)
+	}
+	if c.Complainer < 0 || c.Complainer >= d.n {
+		return nil, fmt.Errorf("complainer index %d out of range", c.Complainer)
+	}
+	d.complaints[c.Dealer][c.Complainer] = true
+	if c.Dealer != d.index {
+		return nil, nil
+	}
+	return &Justification{Dealer: d.index, Share: d.poly.Eval(uint32(c.Complainer))}, nil
+}
+
+// ProcessJustification checks a revealed share against the dealer's commitments. A valid justification answers the
+// complaint and, if it was this participant's complaint, provides its share. An invalid justification disqualifies
+// the dealer.
+func (d *DKG) ProcessJustification(j *Justification) error {
+	if j == nil || j.Share == nil {
+		return errors.New("justification without share")
+	}
+	commits, ok := d.commits[j.Dealer]
+	if !ok {
+		return fmt.Errorf("justification from dealer %d without deal", j.Dealer)
+	}
+	complainer := int(j.Share.I)
+	if !d.complaints[j.Dealer][complainer] {
+		return fmt.Errorf("justification from dealer %d for participant %d without complaint", j.Dealer, complainer)
+	}
+	if j.Share.V == nil || !commits.Check(j.Share) {
+		d.disq[j.Dealer] = true
+		return fmt.Errorf("invalid justification from dealer %d", j.Dealer)
+	}
+	delete(d.complaints[j.Dealer], complainer)
+	if complainer == d.index {
+		d.shares[j.Dealer] = j.Share
+	}
+	return nil
+}
+
+// QUAL returns the sorted indices of the qualified dealers: those that dealt, were not disqualified and answered
+// all complaints.
+func (d *DKG) QUAL() []int {
+	var qual []int
+	for dealer := 0; dealer < d.n; dealer++ {
+		if _, ok := d.commits[dealer]; ok && !d.disq[dealer] && len(d.complaints[dealer]) == 0 {
+			qual = append(qual, dealer)
+		}
+	}
+	return qual
+}
+
+// Finalize computes the participant's share of the master secret key and the commitments to the master polynomial
+// from the deals of all qualified dealers. It must only be called once all deals, complaints and justifications have
+// been processed, so that all honest participants agree on QUAL.
+func (d *DKG) Finalize() (*DistKeyShare, error) {
+	qual := d.QUAL()
+	if len(qual) == 0 {
+		return nil, errors.New("no qualified dealers")
+	}
+	sk := d.gr.Scalar().Zero()
+	var commits *share.PubPoly
+	for _, dealer := range qual {
+		s, ok := d.shares[dealer]
+		if !ok {
+			return nil, fmt.Errorf("missing share from qualified dealer %d", dealer)
+		}
+		sk = sk.Add(sk, s.V)
+		if commits == nil {
+			commits = d.commits[dealer]
+			continue
+		}
+		var err error
+		if commits, err = commits.Add(d.commits[dealer]); err != nil {
+			return nil, err
+		}
+	}
+	// The own polynomial is no longer needed and must not outlive the DKG.
+	for _, c := range d.poly.Coefficients() {
+		c.Zero()
+	}
+	return &DistKeyShare{
+		Share:   &share.PriShare{I: uint32(d.index), V: sk},
+		Commits: commits,
+	}, nil
+}
+
+// RunDKG runs a DKG between n in-process participants with threshold t and returns the key share of every
+// participant.
+func RunDKG(gr kyber.Group, rng cipher.Stream, n, t int) ([]*DistKeyShare, error) {
+	dkgs := make([]*DKG, n)
+	for i := range dkgs {
+		var err error
+		if dkgs[i], err = NewDKG(gr, rng, i, n, t); err != nil {
+			return nil, err
+		}
+	}
+	var complaints []*Complaint
+	for i, dealer := range dkgs {
+		deal, shares := dealer.Deal()
+		for j, p := range dkgs {
+			if j == i {
+				continue
+			}
+			c, err := p.ProcessDeal(deal, shares[j])
+			if err != nil {
+				return nil, err
+			}
+			if c != nil {
+				complaints = append(complaints, c)
+			}
+		}
+	}
+	var justifications []*Justification
+	for _, c := range complaints {
+		for _, p := range dkgs {
+			j, err := p.ProcessComplaint(c)
+			if err != nil {
+				return nil, err
+			}
+			if j != nil {
+				justifications = append(justifications, j)
+			}
+		}
+	}
+	for _, j := range justifications {
+		for _, p := range dkgs {
+			p.ProcessJustification(j)
+		}
+	}
+	keys := make([]*DistKeyShare, n)
+	for i, p := range dkgs {
+		var err error
+		if keys[i], err = p.Finalize(); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
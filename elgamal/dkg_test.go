@@ -0,0 +1,129 @@
+package elgamal_test
+
+import (
+	"btd/elgamal"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/pairing/bls12381/kilic"
+	"go.dedis.ch/kyber/v4/share"
+	"testing"
+)
+
+func TestRunDKG(t *testing.T) {
+	suite := kilic.NewBLS12381Suite()
+	n, th := 7, 4
+	keys, err := elgamal.RunDKG(suite.G1(), suite.RandomStream(), n, th)
+	require.NoError(t, err)
+	for _, k := range keys[1:] {
+		require.True(t, keys[0].Commits.Equal(k.Commits))
+	}
+	// The shares are a sharing of the discrete logarithm of the public key.
+	shares := make([]*share.PriShare, n)
+	for i, k := range keys {
+		require.Equal(t, uint32(i), k.Share.I)
+		require.True(t, k.Commits.Check(k.Share))
+		shares[i] = k.Share
+	}
+	sk, err := share.RecoverSecret(suite.G1(), shares[2:2+th], th, n)
+	require.NoError(t, err)
+	require.True(t, suite.G1().Point().Mul(sk, nil).Equal(keys[0].Public()))
+
+	e := elgamal.NewElGamal(suite.G1(), suite)
+	e.SetKey(keys[0].Commits, n)
+	m := suite.G1().Point().Pick(suite.RandomStream())
+	ct, _ := e.Enc(e.PK, m)
+	d := make([]*elgamal.DecShare, 0, th)
+	for _, i := range []int{0, 3, 5, 6} {
+		s, err := e.PDec(ct, keys[i].Share)
+		require.NoError(t, err)
+		d = append(d, s)
+	}
+	res, err := e.Combine(ct, d)
+	require.NoError(t, err)
+	require.True(t, m.Equal(res))
+}
+
+func TestDKGComplaints(t *testing.T) {
+	suite := kilic.NewBLS12381Suite()
+	n, th := 4, 3
+	dkgs := make([]*elgamal.DKG, n)
+	for i := range dkgs {
+		var err error
+		dkgs[i], err = elgamal.NewDKG(suite.G1(), suite.RandomStream(), i, n, th)
+		require.NoError(t, err)
+	}
+	var complaints []*elgamal.Complaint
+	for i, dealer := range dkgs {
+		deal, shares := dealer.Deal()
+		for j, p := range dkgs {
+			if j == i {
+				continue
+			}
+			s := shares[j]
+			// Dealer 1 sends a wrong share to participant 2, dealer 3 sends none to participant 0.
+			if i == 1 && j == 2 {
+				s = &share.PriShare{I: s.I, V: suite.G1().Scalar().Pick(suite.RandomStream())}
+			}
+			if i == 3 && j == 0 {
+				s = nil
+			}
+			c, err := p.ProcessDeal(deal, s)
+			require.NoError(t, err)
+			if c != nil {
+				complaints = append(complaints, c)
+			}
+		}
+	}
+	require.Len(t, complaints, 2)
+	var justifications []*elgamal.Justification
+	for _, c := range complaints {
+		for _, p := range dkgs {
+			j, err := p.ProcessComplaint(c)
+			require.NoError(t, err)
+			if j != nil {
+				justifications = append(justifications, j)
+			}
+		}
+	}
+	// Dealer 1 answers its complaint, dealer 3 stays silent and is disqualified.
+	for _, j := range justifications {
+		if j.Dealer == 3 {
+			continue
+		}
+		for _, p := range dkgs {
+			require.NoError(t, p.ProcessJustification(j))
+		}
+	}
+	keys := make([]*elgamal.DistKeyShare, n)
+	for i, p := range dkgs {
+		require.Equal(t, []int{0, 1, 2}, p.QUAL())
+		var err error
+		keys[i], err = p.Finalize()
+		require.NoError(t, err)
+		require.True(t, keys[i].Commits.Check(keys[i].Share))
+		require.True(t, keys[0].Public().Equal(keys[i].Public()))
+	}
+
+	// A forged justification disqualifies the dealer.
+	bad := &elgamal.Justification{Dealer: 1, Share: &share.PriShare{I: 2, V: suite.G1().Scalar().One()}}
+	p, err := elgamal.NewDKG(suite.G1(), suite.RandomStream(), 0, n, th)
+	require.NoError(t, err)
+	deal, _ := dkgs[1].Deal()
+	_, err = p.ProcessDeal(deal, nil)
+	require.NoError(t, err)
+	_, err = p.ProcessComplaint(&elgamal.Complaint{Dealer: 1, Complainer: 2})
+	require.NoError(t, err)
+	// Malformed messages are rejected without crashing the participant.
+	require.Error(t, p.ProcessJustification(nil))
+	require.Error(t, p.ProcessJustification(&elgamal.Justification{Dealer: 1}))
+	require.Error(t, p.ProcessJustification(bad))
+	require.NotContains(t, p.QUAL(), 1)
+
+	// So is a deal with a missing commitment, which disqualifies the dealer.
+	deal, shares := dkgs[2].Deal()
+	commits := append([]kyber.Point{}, deal.Commits...)
+	commits[1] = nil
+	_, err = p.ProcessDeal(&elgamal.Deal{Dealer: 2, Commits: commits}, shares[0])
+	require.Error(t, err)
+	require.NotContains(t, p.QUAL(), 2)
+}
@@ -0,0 +1,81 @@
+package elgamal
+
+import (
+	"btd/transcript"
+	"errors"
+	"fmt"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/share"
+)
+
+// DecShare is a partial decryption (g^u)^sk_i together with a proof that it was computed with the secret share
+// sk_i, i.e., that log_{g^u}(V) = log_g(g^sk_i), where g^sk_i is the public verification key of share i.
+type DecShare struct {
+	share.PubShare
+	Proof *DLEQProof
+}
+
+// DLEQProof is a Chaum-Pedersen proof of equality of discrete logarithms in challenge/response form.
+type DLEQProof struct {
+	C kyber.Scalar
+	Z kyber.Scalar
+}
+
+// ErrNoProof is returned when verifying a decryption share that carries no proof.
+var ErrNoProof = errors.New("decryption share carries no proof")
+
+// VerificationKey returns the public verification key g^sk_i of share i.
+func (e *ElGamal) VerificationKey(i uint32) kyber.Point {
+	return e.Commits.Eval(i).V
+}
+
+// proveDLEQ proves that V = x*A and X = x*g for the base point g.
+func (e *ElGamal) proveDLEQ(x kyber.Scalar, X, A, V kyber.Point) (*DLEQProof, error) {
+	r := e.gr.Scalar().Pick(e.rand.RandomStream())
+	T1 := e.gr.Point().Mul(r, nil)
+	T2 := e.gr.Point().Mul(r, A)
+	c, err := dleqChallenge(e.gr, X, A, V, T1, T2)
+	if err != nil {
+		return nil, err
+	}
+	return &DLEQProof{
+		C: c,
+		Z: e.gr.Scalar().Sub(r, e.gr.Scalar().Mul(c, x)),
+	}, nil
+}
+
+func (e *ElGamal) verifyDLEQ(pi *DLEQProof, X, A, V kyber.Point) bool {
+	T1 := e.gr.Point().Add(e.gr.Point().Mul(pi.Z, nil), e.gr.Point().Mul(pi.C, X))
+	T2 := e.gr.Point().Add(e.gr.Point().Mul(pi.Z, A), e.gr.Point().Mul(pi.C, V))
+	c, err := dleqChallenge(e.gr, X, A, V, T1, T2)
+	return err == nil && c.Equal(pi.C)
+}
+
+// dleqChallenge computes the challenge of the Chaum-Pedersen proof for the statement (X, A, V) with the commitments
+// T1 and T2.
+func dleqChallenge(gr kyber.Group, X, A, V, T1, T2 kyber.Point) (kyber.Scalar, error) {
+	t := transcript.New("btd-elgamal-dleq")
+	t.AppendMarshaler("X", X)
+	t.AppendMarshaler("A", A)
+	t.AppendMarshaler("V", V)
+	t.AppendMarshaler("T1", T1)
+	t.AppendMarshaler("T2", T2)
+	return t.Challenge("c", gr)
+}
+
+// VerifyShare checks that the decryption share d of ciphertext c was computed with the secret share of its index.
+func (e *ElGamal) VerifyShare(c CT, d *DecShare) error {
+	if d == nil || d.V == nil {
+		return errors.New("empty decryption share")
+	}
+	if int(d.I) >= e.n {
+		return fmt.Errorf("decryption share index %d out of range [0, %d-1]", d.I, e.n)
+	}
+	if d.Proof == nil || d.Proof.C == nil || d.Proof.Z == nil {
+		return ErrNoProof
+	}
+	if !e.verifyDLEQ(d.Proof, e.VerificationKey(d.I), c.A, d.V) {
+		return fmt.Errorf("invalid proof for decryption share %d", d.I)
+	}
+	return nil
+}
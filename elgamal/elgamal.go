@@ -1,39 +1,37 @@
 package elgamal
 
 import (
-	"crypto/cipher"
-	"fmt"
 	"go.dedis.ch/kyber/v4"
 	"go.dedis.ch/kyber/v4/share"
 )
 
+// ElGamal is threshold ElGamal in the group gr. Once the key is set with KeyGen or SetKey, all other methods are
+// safe for concurrent use: they only read the key and draw their randomness from a fresh stream of rand per call.
 type ElGamal struct {
 	gr      kyber.Group
-	rng     cipher.Stream
-	PK      kyber.Point       // Public key
-	Shares  []*share.PriShare // Shamir Shares
-	Sharing *share.PriPoly
+	rand    kyber.Random
+	PK      kyber.Point    // Public key
+	Commits *share.PubPoly // Commitments to the Shamir sharing of the secret key
 	n, t    int
 }
 
-func NewElGamal(gr kyber.Group, rng cipher.Stream) *ElGamal {
+func NewElGamal(gr kyber.Group, rand kyber.Random) *ElGamal {
 	return &ElGamal{
-		gr:  gr,
-		rng: rng,
+		gr:   gr,
+		rand: rand,
 	}
 }
 
+// CT is an ElGamal ciphertext (A, B) = (g^u, pk^u * m).
 type CT struct {
 	A kyber.Point
 	B kyber.Point
-	m kyber.Point
 }
 
 func (e *ElGamal) NullEGct() CT {
 	return CT{
 		A: e.gr.Point().Null(),
 		B: e.gr.Point().Null(),
-		m: e.gr.Point().Null(),
 	}
 }
 
@@ -41,7 +39,6 @@ func (e *ElGamal) AddCT(a, b CT) CT {
 	return CT{
 		A: e.gr.Point().Add(a.A, b.A),
 		B: e.gr.Point().Add(a.B, b.B),
-		m: e.gr.Point().Add(a.m, b.m),
 	}
 }
 
@@ -53,64 +50,74 @@ func (e *ElGamal) Sum(c []CT) CT {
 	return sum
 }
 
+// KeyGen generates a (t,n)-Shamir sharing of a fresh master secret key with a trusted dealer. The shares are
+// returned and not kept. Use RunDKG and SetKey to generate the key without a trusted dealer.
 func (e *ElGamal) KeyGen(n, t int) ([]*share.PriShare, kyber.Point) {
 	// Sample a random master secret key.
-	sk := e.gr.Scalar().Pick(e.rng)
+	rng := e.rand.RandomStream()
+	sk := e.gr.Scalar().Pick(rng)
 	// Generate (t,n)-Shamir sharing.
-	sharing := share.NewPriPoly(e.gr, t, sk, e.rng)
+	sharing := share.NewPriPoly(e.gr, t, sk, rng)
 	shares := sharing.Shares(n)
 	// Compute master public key
-	pub := sharing.Commit(nil)
-
-	e.Shares = shares
-	e.PK = pub.Commit()
-	e.Sharing = sharing
-	e.n, e.t = n, t
+	e.SetKey(sharing.Commit(nil), n)
 	return shares, e.PK
 }
 
+// SetKey sets the public key from the commitments to a (t,n)-Shamir sharing of the secret key, e.g., the
+// commitments output by a DKG.
+func (e *ElGamal) SetKey(commits *share.PubPoly, n int) {
+	e.Commits = commits
+	e.PK = commits.Commit()
+	e.n, e.t = n, commits.Threshold()
+}
+
 func (e *ElGamal) Enc(pk kyber.Point, m kyber.Point) (CT, kyber.Scalar) {
-	u := e.gr.Scalar().Pick(e.rng) // ephemeral private key
-	A := e.gr.Point().Mul(u, nil)  // ephemeral DH public key
-	S := e.gr.Point().Mul(u, pk)   // ephemeral DH shared secret
-	B := S.Add(S, m)               // message blinded with secret
+	u := e.gr.Scalar().Pick(e.rand.RandomStream()) // ephemeral private key
+	A := e.gr.Point().Mul(u, nil)                  // ephemeral DH public key
+	S := e.gr.Point().Mul(u, pk)                   // ephemeral DH shared secret
+	B := S.Add(S, m)                               // message blinded with secret
 	return CT{
 		A: A,
 		B: B,
-		m: m,
 	}, u
 }
 
-func (e *ElGamal) PDec(c CT, i int) *share.PubShare {
+// PDec computes the decryption share of c with the secret share sk, together with a proof that it was computed
+// with sk.
+func (e *ElGamal) PDec(c CT, sk *share.PriShare) (*DecShare, error) {
 	// Compute (g^u)^sk_i
-	return &share.PubShare{
-		I: e.Shares[i].I,
-		V: e.gr.Point().Mul(e.Shares[i].V, c.A),
+	V := e.gr.Point().Mul(sk.V, c.A)
+	// Prove that the same sk_i is the secret of the verification key g^sk_i
+	X := e.gr.Point().Mul(sk.V, nil)
+	pi, err := e.proveDLEQ(sk.V, X, c.A, V)
+	if err != nil {
+		return nil, err
 	}
+	return &DecShare{
+		PubShare: share.PubShare{I: sk.I, V: V},
+		Proof:    pi,
+	}, nil
 }
 
-func (e *ElGamal) Combine(c CT, shares []*share.PubShare) (kyber.Point, error) {
+// Combine interpolates the decryption shares to decrypt c. The shares are not verified, use VerifyShare for that.
+func (e *ElGamal) Combine(c CT, shares []*DecShare) (kyber.Point, error) {
+	pubs := make([]*share.PubShare, 0, len(shares))
+	for _, d := range shares {
+		if d != nil {
+			pubs = append(pubs, &d.PubShare)
+		}
+	}
 	// Interpolate t shares to compute (g^u)^msk
-	S, err := share.RecoverCommit(e.gr, shares, e.t, e.n)
+	S, err := share.RecoverCommit(e.gr, pubs, e.t, e.n)
 	if err != nil {
 		return nil, err
 	}
 	// Decrypt the message
-	m := e.gr.Point().Sub(c.B, S)
-	// Assertion to check if the decryption is correct
-	if !c.m.Equal(m) {
-		return nil, fmt.Errorf("elgamal decryption failed")
-	}
-	return m, nil
+	return e.gr.Point().Sub(c.B, S), nil
 }
 
-func (e *ElGamal) Dec(sk kyber.Scalar, c CT) (
-	message kyber.Point, err error) {
-
+func (e *ElGamal) Dec(sk kyber.Scalar, c CT) kyber.Point {
 	S := e.gr.Point().Mul(sk, c.A)
-	message = e.gr.Point().Sub(c.B, S)
-	if !c.m.Equal(message) {
-		return nil, fmt.Errorf("elgamal decryption failed")
-	}
-	return message, nil
+	return e.gr.Point().Sub(c.B, S)
 }
@@ -10,14 +10,109 @@ import (
 
 func TestElGamal(t *testing.T) {
 	suite := kilic.NewBLS12381Suite()
-	e := elgamal.NewElGamal(suite.G1(), suite.RandomStream())
-	_, pk := e.KeyGen(10, 5)
+	e := elgamal.NewElGamal(suite.G1(), suite)
+	sks, pk := e.KeyGen(10, 5)
 	m := suite.G1().Point().Pick(suite.RandomStream())
 	ct, _ := e.Enc(pk, m)
-	d := make([]*share.PubShare, 5)
+	d := make([]*elgamal.DecShare, 5)
 	for i := 0; i < 5; i++ {
-		d[i] = e.PDec(ct, i)
+		var err error
+		d[i], err = e.PDec(ct, sks[i])
+		require.NoError(t, err)
 	}
-	_, err := e.Combine(ct, d)
+	res, err := e.Combine(ct, d)
 	require.NoError(t, err)
+	require.True(t, m.Equal(res))
+}
+
+func TestElGamalDec(t *testing.T) {
+	suite := kilic.NewBLS12381Suite()
+	e := elgamal.NewElGamal(suite.G1(), suite)
+	sk := suite.G1().Scalar().Pick(suite.RandomStream())
+	pk := suite.G1().Point().Mul(sk, nil)
+	m1 := suite.G1().Point().Pick(suite.RandomStream())
+	m2 := suite.G1().Point().Pick(suite.RandomStream())
+	ct1, _ := e.Enc(pk, m1)
+	ct2, _ := e.Enc(pk, m2)
+	require.True(t, m1.Equal(e.Dec(sk, ct1)))
+	// Ciphertexts are additively homomorphic.
+	sum := suite.G1().Point().Add(m1, m2)
+	require.True(t, sum.Equal(e.Dec(sk, e.AddCT(ct1, ct2))))
+	require.True(t, sum.Equal(e.Dec(sk, e.Sum([]elgamal.CT{ct1, ct2}))))
+}
+
+func TestVerifyShare(t *testing.T) {
+	suite := kilic.NewBLS12381Suite()
+	e := elgamal.NewElGamal(suite.G1(), suite)
+	sks, pk := e.KeyGen(4, 3)
+	ct, _ := e.Enc(pk, suite.G1().Point().Pick(suite.RandomStream()))
+	d, err := e.PDec(ct, sks[1])
+	require.NoError(t, err)
+	require.NoError(t, e.VerifyShare(ct, d))
+
+	// A share for another ciphertext does not verify.
+	ct2, _ := e.Enc(pk, suite.G1().Point().Pick(suite.RandomStream()))
+	require.Error(t, e.VerifyShare(ct2, d))
+
+	// Neither does a tampered share or a share claiming another index.
+	bad := &elgamal.DecShare{PubShare: share.PubShare{I: d.I, V: suite.G1().Point().Add(d.V, d.V)}, Proof: d.Proof}
+	require.Error(t, e.VerifyShare(ct, bad))
+	bad = &elgamal.DecShare{PubShare: share.PubShare{I: 2, V: d.V}, Proof: d.Proof}
+	require.Error(t, e.VerifyShare(ct, bad))
+	bad = &elgamal.DecShare{PubShare: share.PubShare{I: 4, V: d.V}, Proof: d.Proof}
+	require.Error(t, e.VerifyShare(ct, bad))
+
+	bad = &elgamal.DecShare{PubShare: d.PubShare}
+	require.ErrorIs(t, e.VerifyShare(ct, bad), elgamal.ErrNoProof)
+}
+
+func TestCombineRobust(t *testing.T) {
+	suite := kilic.NewBLS12381Suite()
+	e := elgamal.NewElGamal(suite.G1(), suite)
+	sks, pk := e.KeyGen(7, 3)
+	m := suite.G1().Point().Pick(suite.RandomStream())
+	ct, _ := e.Enc(pk, m)
+	d := make([]*elgamal.DecShare, len(sks))
+	for i, sk := range sks {
+		var err error
+		d[i], err = e.PDec(ct, sk)
+		require.NoError(t, err)
+	}
+	tamper := func(d *elgamal.DecShare) *elgamal.DecShare {
+		V := suite.G1().Point().Pick(suite.RandomStream())
+		return &elgamal.DecShare{PubShare: share.PubShare{I: d.I, V: V}, Proof: d.Proof}
+	}
+
+	// Shares with proofs: the faulty share is identified and skipped.
+	shares := []*elgamal.DecShare{d[0], tamper(d[1]), d[2], d[3], d[4]}
+	res, report, err := e.CombineRobust(ct, shares)
+	require.NoError(t, err)
+	require.True(t, m.Equal(res))
+	require.Equal(t, []uint32{0, 2, 3}, report.Used)
+	require.Equal(t, []uint32{1}, report.Invalid)
+
+	// A conflicting duplicate of a verified share is an equivocation, but the verified share is still used.
+	shares = []*elgamal.DecShare{d[0], tamper(d[0]), d[2], d[3]}
+	res, report, err = e.CombineRobust(ct, shares)
+	require.NoError(t, err)
+	require.True(t, m.Equal(res))
+	require.Equal(t, []uint32{0, 2, 3}, report.Used)
+	require.Empty(t, report.Invalid)
+	require.Equal(t, []uint32{0}, report.Equivocated)
+
+	// Shares without proofs: up to (m-t)/2 faulty shares are corrected.
+	shares = make([]*elgamal.DecShare, len(d))
+	for i := range d {
+		shares[i] = &elgamal.DecShare{PubShare: d[i].PubShare}
+	}
+	shares[0], shares[4] = tamper(shares[0]), tamper(shares[4])
+	res, report, err = e.CombineRobust(ct, shares)
+	require.NoError(t, err)
+	require.True(t, m.Equal(res))
+	require.Equal(t, []uint32{1, 2, 3}, report.Used)
+	require.Equal(t, []uint32{0, 4}, report.Invalid)
+
+	// But not more.
+	_, _, err = e.CombineRobust(ct, shares[:5])
+	require.ErrorIs(t, err, elgamal.ErrTooManyFaults)
 }
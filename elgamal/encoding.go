@@ -0,0 +1,49 @@
+package elgamal
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"go.dedis.ch/kyber/v4/share"
+)
+
+// MarshalBinary encodes the decryption share as index || V || C || Z, where the index is a big-endian uint32 and the
+// elements have their fixed-size encoding. A share without a proof cannot be encoded.
+func (d *DecShare) MarshalBinary() ([]byte, error) {
+	if d.Proof == nil {
+		return nil, ErrNoProof
+	}
+	buf := binary.BigEndian.AppendUint32(nil, uint32(d.I))
+	for _, m := range []encoding.BinaryMarshaler{d.V, d.Proof.C, d.Proof.Z} {
+		b, err := m.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+	}
+	return buf, nil
+}
+
+// UnmarshalDecShare decodes a decryption share encoded with DecShare.MarshalBinary. The share is not verified.
+func (e *ElGamal) UnmarshalDecShare(data []byte) (*DecShare, error) {
+	pointLen, scalarLen := e.gr.PointLen(), e.gr.ScalarLen()
+	if len(data) != 4+pointLen+2*scalarLen {
+		return nil, fmt.Errorf("decryption share has %d bytes, expected %d", len(data), 4+pointLen+2*scalarLen)
+	}
+	d := &DecShare{
+		PubShare: share.PubShare{I: binary.BigEndian.Uint32(data), V: e.gr.Point()},
+		Proof:    &DLEQProof{C: e.gr.Scalar(), Z: e.gr.Scalar()},
+	}
+	data = data[4:]
+	if err := d.V.UnmarshalBinary(data[:pointLen]); err != nil {
+		return nil, fmt.Errorf("invalid decryption share: %w", err)
+	}
+	data = data[pointLen:]
+	if err := d.Proof.C.UnmarshalBinary(data[:scalarLen]); err != nil {
+		return nil, fmt.Errorf("invalid proof challenge: %w", err)
+	}
+	if err := d.Proof.Z.UnmarshalBinary(data[scalarLen:]); err != nil {
+		return nil, fmt.Errorf("invalid proof response: %w", err)
+	}
+	return d, nil
+}
@@ -0,0 +1,150 @@
+package elgamal
+
+import (
+	"errors"
+	"fmt"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/share"
+	"slices"
+)
+
+// CombineReport describes which decryption shares a robust combination used and which it found to be faulty.
+type CombineReport struct {
+	Used        []uint32 // Indices of the t valid shares that were interpolated, which determine the decryption
+	Invalid     []uint32 // Sorted indices of the shares that are out of range or wrong
+	Equivocated []uint32 // Sorted indices with several different shares, of which only the first was considered
+}
+
+// ErrTooManyFaults is returned by CombineRobust if the valid shares cannot be told apart from the faulty ones.
+var ErrTooManyFaults = errors.New("too many faulty decryption shares")
+
+// CombineRobust decrypts c from shares that may contain faulty ones. Shares with a proof are checked with
+// VerifyShare. If at least t of them are valid, the first t valid shares are interpolated and shares without a proof
+// are checked against the interpolated polynomial. Otherwise, the shares without a proof are decoded as a Reed-Solomon
+// code in the exponent: t-subsets of the candidate shares are interpolated until one polynomial agrees with all but
+// e of the m candidates, where e = (m-t)/2 is the number of faults that can be corrected. Decoding enumerates up to
+// (m choose t) subsets, so shares should carry proofs wherever possible.
+//
+// The report is returned even if decryption fails and names all shares that were found to be faulty.
+func (e *ElGamal) CombineRobust(c CT, shares []*DecShare) (kyber.Point, *CombineReport, error) {
+	report := &CombineReport{}
+	invalid := make(map[uint32]bool)
+	equivocated := make(map[uint32]bool)
+	seen := make(map[uint32]*DecShare)
+	var verified, unverified []*DecShare
+	for _, d := range shares {
+		if d == nil || d.V == nil {
+			continue
+		}
+		if s, ok := seen[d.I]; ok {
+			// Only the holder of share i can have sent two different shares for index i. The first share is judged
+			// on its own, so a share that was verified still counts as valid.
+			if !s.V.Equal(d.V) {
+				equivocated[d.I] = true
+			}
+			continue
+		}
+		seen[d.I] = d
+		switch err := e.VerifyShare(c, d); {
+		case err == nil:
+			verified = append(verified, d)
+		case errors.Is(err, ErrNoProof):
+			unverified = append(unverified, d)
+		default:
+			invalid[d.I] = true
+		}
+	}
+	var poly *share.PubPoly
+	var used []*DecShare
+	var err error
+	if len(verified) >= e.t {
+		used = verified[:e.t]
+		poly, err = e.interpolate(used)
+	} else {
+		poly, used, err = e.decode(verified, unverified)
+	}
+	if err == nil {
+		for _, d := range unverified {
+			if !poly.Eval(d.I).V.Equal(d.V) {
+				invalid[d.I] = true
+			}
+		}
+		for _, d := range used {
+			report.Used = append(report.Used, d.I)
+		}
+	}
+	report.Invalid = sortedKeys(invalid)
+	report.Equivocated = sortedKeys(equivocated)
+	if err != nil {
+		return nil, report, err
+	}
+	// The constant term of the polynomial in the exponent of A is (g^u)^msk
+	return e.gr.Point().Sub(c.B, poly.Commit()), report, nil
+}
+
+// sortedKeys returns the indices in set in increasing order.
+func sortedKeys(set map[uint32]bool) []uint32 {
+	var keys []uint32
+	for i := range set {
+		keys = append(keys, i)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// decode finds the polynomial that agrees with all verified shares and with all but at most (m-t)/2 of the m
+// candidate shares. It returns the polynomial and the t shares it was interpolated from.
+func (e *ElGamal) decode(verified, unverified []*DecShare) (*share.PubPoly, []*DecShare, error) {
+	m := len(verified) + len(unverified)
+	if m < e.t {
+		return nil, nil, fmt.Errorf("%d valid or unverified decryption shares, need %d", m, e.t)
+	}
+	need := m - (m-e.t)/2
+	subset := slices.Clip(verified)
+	k := e.t - len(verified)
+	// Enumerate the k-subsets of the unverified shares in lexicographic order.
+	idx := make([]int, k)
+	for i := range idx {
+		idx[i] = i
+	}
+	for {
+		cand := subset
+		for _, i := range idx {
+			cand = append(cand, unverified[i])
+		}
+		poly, err := e.interpolate(cand)
+		if err != nil {
+			return nil, nil, err
+		}
+		agree := len(verified)
+		for _, d := range unverified {
+			if poly.Eval(d.I).V.Equal(d.V) {
+				agree++
+			}
+		}
+		if agree >= need {
+			return poly, cand, nil
+		}
+		// Advance to the next subset.
+		j := k - 1
+		for j >= 0 && idx[j] == len(unverified)-k+j {
+			j--
+		}
+		if j < 0 {
+			return nil, nil, ErrTooManyFaults
+		}
+		idx[j]++
+		for l := j + 1; l < k; l++ {
+			idx[l] = idx[l-1] + 1
+		}
+	}
+}
+
+// interpolate recovers the polynomial in the exponent of A from exactly t decryption shares.
+func (e *ElGamal) interpolate(shares []*DecShare) (*share.PubPoly, error) {
+	pubs := make([]*share.PubShare, len(shares))
+	for i, d := range shares {
+		pubs[i] = &d.PubShare
+	}
+	return share.RecoverPubPoly(e.gr, pubs, e.t, e.n)
+}
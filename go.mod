@@ -3,6 +3,7 @@ module btd
 go 1.22
 
 require (
+	github.com/kilic/bls12-381 v0.1.0
 	github.com/stretchr/testify v1.9.0
 	go.dedis.ch/kyber/v4 v4.0.0-pre2.0.20240806142600-b172e02f7ce5
 )
@@ -10,7 +11,6 @@ require (
 require (
 	github.com/cloudflare/circl v1.3.9 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/kilic/bls12-381 v0.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	go.dedis.ch/fixbuf v1.0.3 // indirect
 	golang.org/x/crypto v0.26.0 // indirect
@@ -1,124 +1,432 @@
+// Command btd runs the batched threshold encryption scheme from the shell. Every step of the committee flow reads
+// and writes files:
+//
+//	btd setup -suite bls12381-kilic -B 8 -crs crs.bin
+//	btd keygen -crs crs.bin -n 10 -t 5 -dir keys        (or btd dkg with the same flags)
+//	btd enc -crs crs.bin -pk keys/pk.bin -i 3 -m "transfer 10 to alice" -batch batch.bin
+//	btd pdec -crs crs.bin -pk keys/pk.bin -sk keys/sk-0.bin -batch batch.bin -out share-0.bin
+//	btd combine -crs crs.bin -pk keys/pk.bin -batch batch.bin share-0.bin share-1.bin ...
+//
+// btd node serves the partial decryptions of one member over HTTP instead, see package node:
+//
+//	btd node -crs crs.bin -pk keys/pk.bin -sk keys/sk-0.bin -addr localhost:7000
+//	btd combine -crs crs.bin -pk keys/pk.bin -batch batch.bin -nodes http://localhost:7000,http://localhost:7001,...
+//
+// setup uses a trusted dealer for the CRS; use the ceremony command to generate it without one. The suite is read
+// from the CRS file, so only setup selects it. btd demo runs the whole flow in-process.
 package main
 
 import (
 	"btd/be"
 	"btd/curves"
+	"btd/elgamal"
+	"btd/node"
+	"btd/prf"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"go.dedis.ch/kyber/v4"
-	"go.dedis.ch/kyber/v4/pairing"
-	"go.dedis.ch/kyber/v4/pairing/bls12381/circl"
-	"go.dedis.ch/kyber/v4/pairing/bls12381/kilic"
-	"go.dedis.ch/kyber/v4/pairing/bn254"
-	"go.dedis.ch/kyber/v4/pairing/bn256"
 	"go.dedis.ch/kyber/v4/share"
-	"math"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 )
 
-func testPairing(suite pairing.Suite, name string, N int) {
-	g1 := make([]kyber.Point, N)
-	g2 := make([]kyber.Point, N)
-	gt := make([]kyber.Point, N)
-	for i := 0; i < N; i++ {
-		g1[i] = suite.G1().Point().Pick(suite.RandomStream())
-		g2[i] = suite.G2().Point().Pick(suite.RandomStream())
-	}
-	fmt.Println("Start")
-	start := time.Now()
-	for j := 0; j < N; j++ {
-		gt[j] = suite.Pair(g1[j], g2[j])
-	}
-	elapsed := time.Since(start)
-	fmt.Printf("%s: Elapsed time for %d pairings: %s\n", name, N, elapsed)
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	commands := map[string]func([]string) error{
+		"setup":   setup,
+		"keygen":  func(args []string) error { return keygen("keygen", args) },
+		"dkg":     func(args []string) error { return keygen("dkg", args) },
+		"enc":     enc,
+		"pdec":    pdec,
+		"combine": combine,
+		"node":    serve,
+		"demo":    demo,
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
 
-func pairingTest() {
-	testPairing(bn254.NewSuiteBn254(), "BN254", 1000)
-	testPairing(bn256.NewSuiteBn256(), "BN256", 1000)
-	testPairing(circl.NewSuiteBLS12381(), "BLS12381-circl", 1000)
-	testPairing(kilic.NewBLS12381Suite(), "BLS12381-kilic", 1000)
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: btd setup|keygen|dkg|enc|pdec|combine|node|demo [flags]")
+	os.Exit(2)
 }
 
-func main() {
-	fmt.Println(int(math.Floor(2.0 * math.Sqrt(float64(512)))))
-	suite := curves.NewSuite(kilic.NewSuiteBLS12381())
-	B := 8
-	n := 10
-	t := 5
-	btd := be.NewBTD(suite, B)
-	_, pk := btd.KeyGen(n, t)
-	fmt.Println("Setup succeeded")
-	m := suite.PickGT()
-	cts := make([]be.CT, B)
-	for i := 0; i < B; i++ {
-		ct, err := btd.Enc(pk, i, m)
-		if err != nil {
-			fmt.Println(err)
-			return
+func suiteFlag(fs *flag.FlagSet) *string {
+	return fs.String("suite", curves.Default, "pairing suite, one of "+strings.Join(curves.Names(), ", ")+
+		", optionally with the suffix :crs-g1")
+}
+
+// crsFlags defines the flags of the CRS file and of skipping its verification.
+func crsFlags(fs *flag.FlagSet) (*string, *bool) {
+	return fs.String("crs", "", "CRS file"), fs.Bool("noverify", false, "skip the verification of the CRS")
+}
+
+// load creates a BTD instance from the CRS file crs and, if pk is set, the public key file pk. Unless noVerify is set,
+// the CRS is verified with prf.VerifyCRS.
+func load(crs string, noVerify bool, pk string) (*be.BTD, error) {
+	if crs == "" {
+		return nil, errors.New("missing -crs")
+	}
+	f, err := prf.LoadCRS(nil, crs)
+	if err != nil {
+		return nil, err
+	}
+	if !noVerify {
+		if err := prf.VerifyCRS(f, true); err != nil {
+			return nil, fmt.Errorf("%s: %w", crs, err)
 		}
-		cts[i] = ct
 	}
-	fmt.Println("Encryption succeeded")
-	testOptSqrt(btd, cts)
+	btd := be.NewBTDWithCRS(f.Suite(), f)
+	if pk == "" {
+		return btd, nil
+	}
+	data, err := os.ReadFile(pk)
+	if err != nil {
+		return nil, err
+	}
+	return btd, btd.UnmarshalKey(data)
+}
+
+// loadBatch reads the batch file path. A missing file is an empty batch.
+func loadBatch(btd *be.BTD, path string) ([]be.BytesCT, error) {
+	if path == "" {
+		return nil, errors.New("missing -batch")
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return btd.UnmarshalBytesBatch(data)
+}
+
+func setup(args []string) error {
+	fs := flag.NewFlagSet("setup", flag.ExitOnError)
+	name := suiteFlag(fs)
+	B := fs.Int("B", 0, "batch size, i.e., the domain size of the CRS")
+	out := fs.String("crs", "", "CRS file to write")
+	fs.Parse(args)
+	if *B < 1 || *out == "" {
+		return errors.New("setup requires -B and -crs")
+	}
+	suite, err := curves.Lookup(*name)
+	if err != nil {
+		return err
+	}
+	if err := prf.PRFSetup(suite, *B, true).SaveCRS(*out); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote CRS for B=%d in suite %s to %s\n", *B, suite.Name(), *out)
+	return nil
 }
 
-func testNaive(btd *be.BTD, cts []be.CT) {
-	d := make([]*share.PubShare, btd.T)
-	var err error
-	for i := 0; i < btd.T; i++ {
-		d[i], err = btd.BatchDec(cts, i, true)
+// keygen generates the committee key with a trusted dealer (keygen) or an in-process DKG (dkg) and writes the public
+// key to pk.bin and the key share of member i to sk-<i>.bin in the output directory.
+func keygen(cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	crs, noVerify := crsFlags(fs)
+	n := fs.Int("n", 0, "number of committee members")
+	t := fs.Int("t", 0, "number of decryption shares needed to decrypt")
+	dir := fs.String("dir", ".", "directory to write the key files to")
+	fs.Parse(args)
+	if *t < 1 || *n < *t {
+		return fmt.Errorf("%s requires 1 <= -t <= -n", cmd)
+	}
+	btd, err := load(*crs, *noVerify, "")
+	if err != nil {
+		return err
+	}
+	var sks []*share.PriShare
+	if cmd == "dkg" {
+		keys, _, err := btd.KeyGenDKG(*n, *t)
 		if err != nil {
-			panic(err)
+			return err
+		}
+		for _, key := range keys {
+			sks = append(sks, key.Share)
 		}
+	} else {
+		sks, _ = btd.KeyGen(*n, *t)
 	}
-	count, err := btd.BatchCombine(cts, d, false)
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	pk, err := btd.MarshalKey()
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(*dir, "pk.bin"), pk, 0o644); err != nil {
+		return err
+	}
+	for _, sk := range sks {
+		data, err := btd.MarshalKeyShare(sk)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(*dir, fmt.Sprintf("sk-%d.bin", sk.I)), data, 0o600); err != nil {
+			return err
+		}
 	}
-	fmt.Println("Decryption succeeded")
-	fmt.Println("Pairings for Dec:", count)
+	fmt.Printf("Wrote public key and %d key shares with threshold %d to %s\n", *n, *t, *dir)
+	return nil
 }
 
-func testOpt(btd *be.BTD, cts []be.CT) {
-	ds := make([][]*share.PubShare, btd.T)
-	var err error
-	for i := 0; i < btd.T; i++ {
-		ds[i], err = btd.BatchDecOpt(cts, i, true)
-		if err != nil {
-			panic(err)
+func enc(args []string) error {
+	fs := flag.NewFlagSet("enc", flag.ExitOnError)
+	crs, noVerify := crsFlags(fs)
+	pk := fs.String("pk", "", "public key file")
+	i := fs.Int("i", -1, "index of the ciphertext in the batch")
+	msg := fs.String("m", "", "message to encrypt")
+	in := fs.String("in", "", "file with the message to encrypt, instead of -m")
+	ad := fs.String("ad", "", "associated data the ciphertext is bound to")
+	batch := fs.String("batch", "", "batch file to append the ciphertext to, created if missing")
+	fs.Parse(args)
+	if *pk == "" {
+		return errors.New("enc requires -pk")
+	}
+	btd, err := load(*crs, *noVerify, *pk)
+	if err != nil {
+		return err
+	}
+	m := []byte(*msg)
+	if *in != "" {
+		if m, err = os.ReadFile(*in); err != nil {
+			return err
 		}
 	}
-	count, err := btd.BatchCombineOpt(cts, ds, false)
+	cts, err := loadBatch(btd, *batch)
+	if err != nil {
+		return err
+	}
+	ct, err := btd.EncBytes(btd.PublicKey(), *i, m, []byte(*ad))
+	if err != nil {
+		return err
+	}
+	cts = append(cts, ct)
+	if _, err := btd.EmptySlots(be.KEMs(cts)); err != nil {
+		return err
+	}
+	data, err := be.MarshalBytesBatch(cts)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*batch, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Appended ciphertext on index %d to %s (%d ciphertexts)\n", *i, *batch, len(cts))
+	return nil
+}
+
+func pdec(args []string) error {
+	fs := flag.NewFlagSet("pdec", flag.ExitOnError)
+	crs, noVerify := crsFlags(fs)
+	pk := fs.String("pk", "", "public key file")
+	skPath := fs.String("sk", "", "key share file")
+	batch := fs.String("batch", "", "batch file")
+	ad := fs.String("ad", "", "associated data all ciphertexts of the batch must be bound to")
+	out := fs.String("out", "", "decryption share file to write")
+	fs.Parse(args)
+	if *pk == "" || *skPath == "" || *out == "" {
+		return errors.New("pdec requires -pk, -sk and -out")
+	}
+	btd, err := load(*crs, *noVerify, *pk)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*skPath)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	sk, err := btd.UnmarshalKeyShare(data)
+	if err != nil {
+		return err
+	}
+	cts, err := loadBatch(btd, *batch)
+	if err != nil {
+		return err
+	}
+	// The associated data and the proofs of all ciphertexts are checked before the batch is decrypted.
+	if err := be.CheckAD(be.KEMs(cts), []byte(*ad)); err != nil {
+		return err
+	}
+	d, err := btd.BatchDecBytes(cts, sk, true)
+	if err != nil {
+		return err
+	}
+	if data, err = btd.MarshalDecShare(d); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		return err
 	}
-	fmt.Println("Optimized Decryption succeeded")
-	fmt.Println("Pairings for optimized Dec:", count)
+	fmt.Printf("Wrote decryption share %d of %d ciphertexts to %s\n", sk.I, len(cts), *out)
+	return nil
 }
 
-func testOptSqrt(btd *be.BTD, cts []be.CT) {
-	sqrtB := int(math.Floor(math.Sqrt(float64(btd.B))))
-	count := 0
-	for i := 0; i < sqrtB; i++ {
-		start := i * sqrtB
-		end := (i + 1) * sqrtB
-		if i == sqrtB-1 {
-			end = btd.B
+// combine decrypts the batch with the decryption share files given as arguments, or with the shares collected from
+// the nodes given with -nodes. The plaintexts are printed, or written to <index>.bin in the output directory.
+func combine(args []string) error {
+	fs := flag.NewFlagSet("combine", flag.ExitOnError)
+	crs, noVerify := crsFlags(fs)
+	pk := fs.String("pk", "", "public key file")
+	batch := fs.String("batch", "", "batch file")
+	ad := fs.String("ad", "", "associated data all ciphertexts of the batch must be bound to")
+	dir := fs.String("dir", "", "directory to write the plaintexts to instead of printing them")
+	nodes := fs.String("nodes", "", "comma-separated URLs of the nodes to collect the decryption shares from")
+	timeout := fs.Duration("timeout", 30*time.Second, "deadline for collecting the decryption shares from the nodes")
+	fs.Parse(args)
+	if *pk == "" {
+		return errors.New("combine requires -pk")
+	}
+	btd, err := load(*crs, *noVerify, *pk)
+	if err != nil {
+		return err
+	}
+	cts, err := loadBatch(btd, *batch)
+	if err != nil {
+		return err
+	}
+	empty, err := btd.EmptySlots(be.KEMs(cts))
+	if err != nil {
+		return err
+	}
+	if err := be.CheckAD(be.KEMs(cts), []byte(*ad)); err != nil {
+		return err
+	}
+	var d []*elgamal.DecShare
+	if *nodes != "" {
+		if d, err = collect(btd, be.KEMs(cts), []byte(*ad), strings.Split(*nodes, ","), *timeout); err != nil {
+			return err
+		}
+	} else if d, err = readShares(btd, fs.Args()); err != nil {
+		return err
+	}
+	// Payloads that fail to decrypt are reported in err, but do not prevent the others from being output. The
+	// shares collected from nodes have been verified with the batch already.
+	ms, err := btd.BatchCombineBytes(cts, d, *nodes == "")
+	if ms == nil {
+		return err
+	}
+	if *dir != "" {
+		if err := os.MkdirAll(*dir, 0o755); err != nil {
+			return err
+		}
+	}
+	for k, m := range ms {
+		if m == nil {
+			continue
 		}
-		ds := make([][]*share.PubShare, btd.T)
-		var err error
-		for j := 0; j < btd.T; j++ {
-			ds[j], err = btd.BatchDecOpt(cts[start:end], j, true)
-			if err != nil {
-				panic(err)
-			}
+		if *dir == "" {
+			fmt.Printf("%d: %q\n", cts[k].Index(), m)
+			continue
 		}
-		x, err := btd.BatchCombineOpt(cts[start:end], ds, false)
+		if err := os.WriteFile(filepath.Join(*dir, fmt.Sprintf("%d.bin", cts[k].Index())), m, 0o644); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Decrypted %d ciphertexts, empty slots: %v\n", len(cts), empty)
+	return err
+}
+
+// readShares reads the decryption share files paths.
+func readShares(btd *be.BTD, paths []string) ([]*elgamal.DecShare, error) {
+	if len(paths) < btd.T {
+		return nil, fmt.Errorf("combine requires %d decryption share files, got %d", btd.T, len(paths))
+	}
+	d := make([]*elgamal.DecShare, len(paths))
+	for k, path := range paths {
+		data, err := os.ReadFile(path)
 		if err != nil {
-			panic(err)
+			return nil, err
+		}
+		if d[k], err = btd.UnmarshalDecShare(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
 		}
-		count += x
 	}
-	fmt.Println("Optimized Decryption with sqrt(B)*log(sqrt(B)) communication succeeded")
-	fmt.Println("Pairings for optimized Dec:", count)
+	return d, nil
+}
+
+// collect collects the decryption shares of cts, bound to the associated data ad, from the nodes at urls and reports
+// the nodes that failed or were late.
+func collect(btd *be.BTD, cts []be.CT, ad []byte, urls []string, timeout time.Duration) ([]*elgamal.DecShare, error) {
+	clients := make([]*node.Client, len(urls))
+	for k, url := range urls {
+		clients[k] = node.NewClient(btd, url)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	r, err := node.NewAggregator(btd, clients).Collect(ctx, cts, ad)
+	if r != nil {
+		for k, err := range r.Failed {
+			fmt.Fprintf(os.Stderr, "Node %s failed: %v\n", urls[k], err)
+		}
+		for _, k := range r.Late {
+			fmt.Fprintf(os.Stderr, "Node %s was late\n", urls[k])
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.Shares, nil
+}
+
+// serve runs the node of a committee member until it is interrupted.
+func serve(args []string) error {
+	fs := flag.NewFlagSet("node", flag.ExitOnError)
+	crs, noVerify := crsFlags(fs)
+	pk := fs.String("pk", "", "public key file")
+	skPath := fs.String("sk", "", "key share file")
+	addr := fs.String("addr", "localhost:7000", "address to listen on")
+	maxBatch := fs.Int64("max-batch", node.DefaultMaxBatchBytes, "maximum size of a batch in bytes")
+	fs.Parse(args)
+	if *pk == "" || *skPath == "" {
+		return errors.New("node requires -pk and -sk")
+	}
+	btd, err := load(*crs, *noVerify, *pk)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(*skPath)
+	if err != nil {
+		return err
+	}
+	sk, err := btd.UnmarshalKeyShare(data)
+	if err != nil {
+		return err
+	}
+	nd, err := node.New(btd, sk)
+	if err != nil {
+		return err
+	}
+	nd.MaxBatchBytes = *maxBatch
+	srv := &http.Server{Addr: *addr, Handler: nd, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(os.Stderr, "Node %d of %d serving on %s\n", sk.I, btd.N, *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
@@ -3,23 +3,45 @@ package main_test
 import (
 	"btd/be"
 	"btd/curves"
+	"btd/elgamal"
+	"context"
+	"flag"
 	"fmt"
 	"go.dedis.ch/kyber/v4"
-	"go.dedis.ch/kyber/v4/pairing/bls12381/kilic"
 	"go.dedis.ch/kyber/v4/share"
+	"io"
 	"math"
+	"runtime"
+	"strings"
 	"sync"
 	"testing"
 )
 
-var Suite curves.Suite
+var suiteFlag = flag.String("suite", "",
+	"comma-separated names of the suites to benchmark, all registered suites if empty")
 
-func init() {
-	Suite = curves.NewSuite(kilic.NewBLS12381Suite())
+// forEachSuite runs bench as a sub-benchmark for each suite selected with -suite.
+func forEachSuite(b *testing.B, bench func(b *testing.B, suite curves.Suite)) {
+	names := curves.Names()
+	if *suiteFlag != "" {
+		names = strings.Split(*suiteFlag, ",")
+	}
+	for _, name := range names {
+		suite, err := curves.Lookup(name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(name, func(b *testing.B) {
+			bench(b, suite)
+		})
+	}
 }
 
 func BenchmarkEnc(b *testing.B) {
-	suite := Suite
+	forEachSuite(b, testBenchmarkEnc)
+}
+
+func testBenchmarkEnc(b *testing.B, suite curves.Suite) {
 	B := 16
 	btd := be.NewBTD(suite, B)
 	R := 100
@@ -32,7 +54,7 @@ func BenchmarkEnc(b *testing.B) {
 	_, pk := btd.KeyGen(n, t)
 	b.StartTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := btd.Enc(pk, 0, Ms[i%R])
+		_, err := btd.Enc(pk, 0, Ms[i%R], nil)
 		if err != nil {
 			b.Error(err)
 		}
@@ -41,22 +63,29 @@ func BenchmarkEnc(b *testing.B) {
 }
 
 func BenchmarkPDec8(b *testing.B) {
-	testBenchmarkPDec(b, 8)
+	forEachSuite(b, func(b *testing.B, suite curves.Suite) {
+		testBenchmarkPDec(b, suite, 8)
+	})
 }
 
 func BenchmarkPDec32(b *testing.B) {
-	testBenchmarkPDec(b, 32)
+	forEachSuite(b, func(b *testing.B, suite curves.Suite) {
+		testBenchmarkPDec(b, suite, 32)
+	})
 }
 func BenchmarkPDec128(b *testing.B) {
-	testBenchmarkPDec(b, 128)
+	forEachSuite(b, func(b *testing.B, suite curves.Suite) {
+		testBenchmarkPDec(b, suite, 128)
+	})
 }
 
 func BenchmarkPDec512(b *testing.B) {
-	testBenchmarkPDec(b, 512)
+	forEachSuite(b, func(b *testing.B, suite curves.Suite) {
+		testBenchmarkPDec(b, suite, 512)
+	})
 }
 
-func testBenchmarkPDec(b *testing.B, B int) {
-	suite := Suite
+func testBenchmarkPDec(b *testing.B, suite curves.Suite, B int) {
 	btd := be.NewBTD(suite, B)
 	n := 10
 	t := 5
@@ -66,12 +95,12 @@ func testBenchmarkPDec(b *testing.B, B int) {
 		Ms[i] = suite.PickGT()
 
 	}
-	_, pk := btd.KeyGen(n, t)
+	sks, pk := btd.KeyGen(n, t)
 	ctsR := make([][]be.CT, R)
 	for j := 0; j < R; j++ {
 		cts := make([]be.CT, B)
 		for i := 0; i < B; i++ {
-			ct, err := btd.Enc(pk, i, Ms[j])
+			ct, err := btd.Enc(pk, i, Ms[j], nil)
 			if err != nil {
 				b.Error(err)
 			}
@@ -79,64 +108,100 @@ func testBenchmarkPDec(b *testing.B, B int) {
 		}
 		ctsR[j] = cts
 	}
-	// normal (unoptimized)
+	// normal (unoptimized): every proof checked on its own
 	b.Run(fmt.Sprintf("normal: B=%d", B), func(b *testing.B) {
-		testBatchDec(b, R, B, btd, ctsR)
+		testBatchDec(b, R, B, btd, sks, ctsR)
+	})
+	// proofs checked with a single batch verification
+	b.Run(fmt.Sprintf("batch-verified: B=%d", B), func(b *testing.B) {
+		testBatchDecVerifyBatch(b, R, B, btd, sks, ctsR)
 	})
 
 	factor := 1.0 // alpha = Sqrt(B) -- OPT-1
 	b.Run(fmt.Sprintf("B=%d, alpha=%1f*sqrt(B)", B, factor), func(b *testing.B) {
-		testBatchDecSqrt(b, R, B, btd, factor, ctsR)
+		testBatchDecSqrt(b, R, B, btd, factor, sks, ctsR)
 	})
 	factor = 2.0 // alpha = 2*Sqrt(B) -- OPT-2
 	b.Run(fmt.Sprintf("B=%d, alpha=%1f*sqrt(B)", B, factor), func(b *testing.B) {
-		testBatchDecSqrt(b, R, B, btd, factor, ctsR)
+		testBatchDecSqrt(b, R, B, btd, factor, sks, ctsR)
 	})
 
 	//b.Run(fmt.Sprintf("sqrtlog: B=%d", B), func(b *testing.B) {
-	//	testBatchDecSqrtLog(b, R, B, btd, ctsR)
+	//	testBatchDecSqrtLog(b, R, B, btd, sks, ctsR)
 	//})
 
 }
 
-func testBatchDec(b *testing.B, R, B int, btd *be.BTD, ctsR [][]be.CT) {
+func testBatchDec(b *testing.B, R, B int, btd *be.BTD, sks []*share.PriShare, ctsR [][]be.CT) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := btd.BatchDec(ctsR[i%R][:B], 0, true)
+		for _, ct := range ctsR[i%R][:B] {
+			if err := btd.VerifyCT(ct); err != nil {
+				b.Error(err)
+			}
+		}
+		_, err := btd.BatchDec(ctsR[i%R][:B], sks[0], false)
 		if err != nil {
 			b.Error(err)
 		}
 	}
 }
 
-func testBatchDecSqrt(b *testing.B, R, B int, btd *be.BTD, factor float64, ctsR [][]be.CT) {
-	SubCtsR := make([][][]be.CT, R)
-	alpha := int(math.Floor(factor * math.Sqrt(float64(B))))
-	subBatchLen := float64(B) / float64(alpha)
-	for i := 0; i < R; i++ {
-		SubCtsR[i] = make([][]be.CT, alpha)
-		for j := 0; j < alpha; j++ {
-			start := int(math.Round(float64(j) * subBatchLen))
-			end := int(math.Round(float64(j+1) * subBatchLen))
-			if j == alpha-1 {
-				end = B
-			}
-			SubCtsR[i][j] = ctsR[i][start:end]
+func testBatchDecVerifyBatch(b *testing.B, R, B int, btd *be.BTD, sks []*share.PriShare, ctsR [][]be.CT) {
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := btd.VerifyBatch(ctsR[i%R][:B]); err != nil {
+			b.Error(err)
+		}
+		_, err := btd.BatchDec(ctsR[i%R][:B], sks[0], false)
+		if err != nil {
+			b.Error(err)
 		}
 	}
+}
+
+func testBatchDecSqrt(b *testing.B, R, B int, btd *be.BTD, factor float64, sks []*share.PriShare, ctsR [][]be.CT) {
+	partsR := partitionR(R, B, factor, ctsR)
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		for j := 0; j < alpha; j++ {
-			_, err := btd.BatchDec(SubCtsR[i%R][j], 0, true)
+		_, err := btd.BatchDecSub(partsR[i%R], sks[0], true)
+		if err != nil {
+			b.Error(err)
+		}
+	}
+}
+
+// partitionR splits the first B ciphertexts of each of the R batches into SqrtAlpha(B, factor) sub-batches.
+func partitionR(R, B int, factor float64, ctsR [][]be.CT) []be.Partition {
+	partsR := make([]be.Partition, R)
+	for r := 0; r < R; r++ {
+		p, err := be.NewPartition(ctsR[r][:B], be.SqrtAlpha(B, factor))
+		if err != nil {
+			panic(err)
+		}
+		partsR[r] = p
+	}
+	return partsR
+}
+
+// subShares computes the decryption shares of the first t committee members for all sub-batches of the partitions.
+func subShares(btd *be.BTD, t int, sks []*share.PriShare, partsR []be.Partition) [][][]*elgamal.DecShare {
+	pdecs := make([][][]*elgamal.DecShare, len(partsR))
+	for r, p := range partsR {
+		pdecs[r] = make([][]*elgamal.DecShare, t)
+		for thresh := 0; thresh < t; thresh++ {
+			ds, err := btd.BatchDecSub(p, sks[thresh], false)
 			if err != nil {
-				b.Error(err)
+				panic(err)
 			}
+			pdecs[r][thresh] = ds
 		}
 	}
+	return pdecs
 }
 
-func testBatchDecSqrtLog(b *testing.B, R, B int, btd *be.BTD, ctsR [][]be.CT) {
+func testBatchDecSqrtLog(b *testing.B, R, B int, btd *be.BTD, sks []*share.PriShare, ctsR [][]be.CT) {
 	SubCtsR := make([][][]be.CT, R)
 	sqrtB := int(math.Floor(math.Sqrt(float64(B))))
 	for i := 0; i < R; i++ {
@@ -154,7 +219,7 @@ func testBatchDecSqrtLog(b *testing.B, R, B int, btd *be.BTD, ctsR [][]be.CT) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		for j := 0; j < sqrtB; j++ {
-			_, err := btd.BatchDecOpt(SubCtsR[i%R][j], 0, true)
+			_, err := btd.BatchDecOpt(SubCtsR[i%R][j], sks[0], true)
 			if err != nil {
 				b.Error(err)
 			}
@@ -163,27 +228,36 @@ func testBatchDecSqrtLog(b *testing.B, R, B int, btd *be.BTD, ctsR [][]be.CT) {
 }
 
 func BenchmarkBatchCombine8(b *testing.B) {
-	testBenchmarkBatchCombine(b, 8, false)
+	forEachSuite(b, func(b *testing.B, suite curves.Suite) {
+		testBenchmarkBatchCombine(b, suite, 8, false)
+	})
 }
 
 func BenchmarkBatchCombine32(b *testing.B) {
-	testBenchmarkBatchCombine(b, 32, false)
+	forEachSuite(b, func(b *testing.B, suite curves.Suite) {
+		testBenchmarkBatchCombine(b, suite, 32, false)
+	})
 }
 
 func BenchmarkBatchCombine128(b *testing.B) {
-	testBenchmarkBatchCombine(b, 128, false)
+	forEachSuite(b, func(b *testing.B, suite curves.Suite) {
+		testBenchmarkBatchCombine(b, suite, 128, false)
+	})
 }
 
 func BenchmarkBatchCombine512Slow(b *testing.B) {
-	testBenchmarkBatchCombine(b, 512, true)
+	forEachSuite(b, func(b *testing.B, suite curves.Suite) {
+		testBenchmarkBatchCombine(b, suite, 512, true)
+	})
 }
 
 func BenchmarkBatchCombine512Fast(b *testing.B) {
-	testBenchmarkBatchCombine(b, 512, false)
+	forEachSuite(b, func(b *testing.B, suite curves.Suite) {
+		testBenchmarkBatchCombine(b, suite, 512, false)
+	})
 }
 
-func testBenchmarkBatchCombine(b *testing.B, B int, slow bool) {
-	suite := Suite
+func testBenchmarkBatchCombine(b *testing.B, suite curves.Suite, B int, slow bool) {
 	btd := be.NewBTD(suite, B)
 	n := 10
 	t := 2
@@ -193,12 +267,12 @@ func testBenchmarkBatchCombine(b *testing.B, B int, slow bool) {
 		Ms[i] = suite.PickGT()
 
 	}
-	_, pk := btd.KeyGen(n, t)
+	sks, pk := btd.KeyGen(n, t)
 	ctsR := make([][]be.CT, R)
 	for j := 0; j < R; j++ {
 		cts := make([]be.CT, B)
 		for i := 0; i < B; i++ {
-			ct, err := btd.Enc(pk, i, Ms[j])
+			ct, err := btd.Enc(pk, i, Ms[j], nil)
 			if err != nil {
 				b.Error(err)
 			}
@@ -208,20 +282,24 @@ func testBenchmarkBatchCombine(b *testing.B, B int, slow bool) {
 	}
 	if B < 512 || slow {
 		b.Run(fmt.Sprintf("normal: B=%d", B), func(b *testing.B) {
-			testCombine(b, R, B, t, btd, ctsR)
+			testCombine(b, R, B, t, btd, sks, ctsR)
+		})
+		workers := runtime.NumCPU()
+		b.Run(fmt.Sprintf("parallel: B=%d, workers=%d", B, workers), func(b *testing.B) {
+			testCombineParallel(b, R, B, t, workers, btd, sks, ctsR)
 		})
 	}
 	if B < 512 || !slow {
 		factor := 1.0 // OPT-1 (alpha = sqrt(B))
 		b.Run(fmt.Sprintf("B=%d, alpha=%1f*sqrt(B)", B, factor), func(b *testing.B) {
-			testCombineSqrt(b, R, B, t, factor, btd, ctsR)
+			testCombineSqrt(b, R, B, t, factor, btd, sks, ctsR)
 		})
 		factor = 2.0 // OPT-2 (alpha = 2*sqrt(B))
 		b.Run(fmt.Sprintf("B=%d, alpha=%1f*sqrt(B)", B, factor), func(b *testing.B) {
-			testCombineSqrt(b, R, B, t, factor, btd, ctsR)
+			testCombineSqrt(b, R, B, t, factor, btd, sks, ctsR)
 		})
 		//b.Run(fmt.Sprintf("sqrtlog: B=%d", B), func(b *testing.B) {
-		//	testCombineSqrtOpt(b, R, B, t, btd, ctsR)
+		//	testCombineSqrtOpt(b, R, B, t, btd, sks, ctsR)
 
 		//})
 	}
@@ -230,12 +308,12 @@ func testBenchmarkBatchCombine(b *testing.B, B int, slow bool) {
 	}
 }
 
-func testCombine(b *testing.B, R, B, t int, btd *be.BTD, ctsR [][]be.CT) {
-	pdecs := make([][]*share.PubShare, R)
+func testCombine(b *testing.B, R, B, t int, btd *be.BTD, sks []*share.PriShare, ctsR [][]be.CT) {
+	pdecs := make([][]*elgamal.DecShare, R)
 	for i := 0; i < R; i++ {
-		pdecs[i] = make([]*share.PubShare, t)
+		pdecs[i] = make([]*elgamal.DecShare, t)
 		for j := 0; j < t; j++ {
-			pdec, err := btd.BatchDec(ctsR[i][:B], j, false)
+			pdec, err := btd.BatchDec(ctsR[i][:B], sks[j], false)
 			if err != nil {
 				b.Error(err)
 			}
@@ -251,48 +329,41 @@ func testCombine(b *testing.B, R, B, t int, btd *be.BTD, ctsR [][]be.CT) {
 	}
 }
 
-func testCombineSqrt(b *testing.B, R, B, t int, factor float64, btd *be.BTD, ctsR [][]be.CT) {
-	SubCtsR := make([][][]be.CT, R)
-	alpha := int(math.Floor(factor * math.Sqrt(float64(B))))
-	subBatchLen := float64(B) / float64(alpha)
-	for r := 0; r < R; r++ {
-		SubCtsR[r] = make([][]be.CT, alpha)
-		for j := 0; j < alpha; j++ {
-			start := int(math.Round(float64(j) * subBatchLen))
-			end := int(math.Round(float64(j+1) * subBatchLen))
-			if j == alpha-1 {
-				end = B
+func testCombineParallel(b *testing.B, R, B, t, workers int, btd *be.BTD, sks []*share.PriShare, ctsR [][]be.CT) {
+	pdecs := make([][]*elgamal.DecShare, R)
+	for i := 0; i < R; i++ {
+		pdecs[i] = make([]*elgamal.DecShare, t)
+		for j := 0; j < t; j++ {
+			pdec, err := btd.BatchDec(ctsR[i][:B], sks[j], false)
+			if err != nil {
+				b.Error(err)
 			}
-			SubCtsR[r][j] = ctsR[r][start:end]
+			pdecs[i][j] = pdec
 		}
 	}
-	pdecs := make([][][]*share.PubShare, R)
-	for r := 0; r < R; r++ {
-		pdecs[r] = make([][]*share.PubShare, alpha)
-		for j := 0; j < alpha; j++ {
-			pdecs[r][j] = make([]*share.PubShare, t)
-			for thresh := 0; thresh < t; thresh++ {
-				d, err := btd.BatchDec(SubCtsR[r][j], thresh, false)
-				if err != nil {
-					panic(err)
-				}
-				pdecs[r][j][thresh] = d
-			}
-
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := btd.BatchCombineParallel(ctx, ctsR[i%R][:B], pdecs[i%R], false, workers)
+		if err != nil {
+			b.Error(err)
 		}
 	}
+}
+
+func testCombineSqrt(b *testing.B, R, B, t int, factor float64, btd *be.BTD, sks []*share.PriShare, ctsR [][]be.CT) {
+	partsR := partitionR(R, B, factor, ctsR)
+	pdecs := subShares(btd, t, sks, partsR)
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		for j := 0; j < alpha; j++ {
-			_, err := btd.BatchCombine(SubCtsR[i%R][j], pdecs[i%R][j], false)
-			if err != nil {
-				panic(err)
-			}
+		_, err := btd.BatchCombineSub(partsR[i%R], pdecs[i%R], false)
+		if err != nil {
+			panic(err)
 		}
 	}
 }
 
-func testCombineSqrtOpt(b *testing.B, R, B, t int, btd *be.BTD, ctsR [][]be.CT) {
+func testCombineSqrtOpt(b *testing.B, R, B, t int, btd *be.BTD, sks []*share.PriShare, ctsR [][]be.CT) {
 	SubCtsR := make([][][]be.CT, R)
 	sqrtB := int(math.Floor(math.Sqrt(float64(B))))
 	for r := 0; r < R; r++ {
@@ -306,13 +377,13 @@ func testCombineSqrtOpt(b *testing.B, R, B, t int, btd *be.BTD, ctsR [][]be.CT)
 			SubCtsR[r][j] = ctsR[r][start:end]
 		}
 	}
-	pdecs := make([][][][]*share.PubShare, R)
+	pdecs := make([][][][]*elgamal.DecShare, R)
 	for r := 0; r < R; r++ {
-		pdecs[r] = make([][][]*share.PubShare, sqrtB)
+		pdecs[r] = make([][][]*elgamal.DecShare, sqrtB)
 		for j := 0; j < sqrtB; j++ {
-			pdecs[r][j] = make([][]*share.PubShare, t)
+			pdecs[r][j] = make([][]*elgamal.DecShare, t)
 			for thresh := 0; thresh < t; thresh++ {
-				d, err := btd.BatchDecOpt(SubCtsR[r][j], thresh, false)
+				d, err := btd.BatchDecOpt(SubCtsR[r][j], sks[thresh], false)
 				if err != nil {
 					panic(err)
 				}
@@ -332,7 +403,7 @@ func testCombineSqrtOpt(b *testing.B, R, B, t int, btd *be.BTD, ctsR [][]be.CT)
 	}
 }
 
-func testCombineSqrtOptParallel(b *testing.B, R, B, t int, btd *be.BTD, ctsR [][]be.CT) {
+func testCombineSqrtOptParallel(b *testing.B, R, B, t int, btd *be.BTD, sks []*share.PriShare, ctsR [][]be.CT) {
 	SubCtsR := make([][][]be.CT, R)
 	sqrtB := int(math.Floor(math.Sqrt(float64(B))))
 	for r := 0; r < R; r++ {
@@ -346,13 +417,13 @@ func testCombineSqrtOptParallel(b *testing.B, R, B, t int, btd *be.BTD, ctsR [][
 			SubCtsR[r][j] = ctsR[r][start:end]
 		}
 	}
-	pdecs := make([][][][]*share.PubShare, R)
+	pdecs := make([][][][]*elgamal.DecShare, R)
 	for r := 0; r < R; r++ {
-		pdecs[r] = make([][][]*share.PubShare, sqrtB)
+		pdecs[r] = make([][][]*elgamal.DecShare, sqrtB)
 		for j := 0; j < sqrtB; j++ {
-			pdecs[r][j] = make([][]*share.PubShare, t)
+			pdecs[r][j] = make([][]*elgamal.DecShare, t)
 			for thresh := 0; thresh < t; thresh++ {
-				d, err := btd.BatchDecOpt(SubCtsR[r][j], thresh, false)
+				d, err := btd.BatchDecOpt(SubCtsR[r][j], sks[thresh], false)
 				if err != nil {
 					panic(err)
 				}
@@ -366,7 +437,7 @@ func testCombineSqrtOptParallel(b *testing.B, R, B, t int, btd *be.BTD, ctsR [][
 	for i := 0; i < b.N; i++ {
 		for j := 0; j < sqrtB; j++ {
 			wg.Add(1)
-			go func(ctsSubBatch []be.CT, shares [][]*share.PubShare) {
+			go func(ctsSubBatch []be.CT, shares [][]*elgamal.DecShare) {
 				defer wg.Done()
 				_, err := btd.BatchCombineOpt(ctsSubBatch, shares, false)
 				if err != nil {
@@ -380,7 +451,10 @@ func testCombineSqrtOptParallel(b *testing.B, R, B, t int, btd *be.BTD, ctsR [][
 }
 
 func BenchmarkBatchCombineParSqrt(b *testing.B) {
-	suite := Suite
+	forEachSuite(b, testBenchmarkBatchCombineParSqrt)
+}
+
+func testBenchmarkBatchCombineParSqrt(b *testing.B, suite curves.Suite) {
 	B := 512
 	btd := be.NewBTD(suite, B)
 	n := 10
@@ -391,12 +465,12 @@ func BenchmarkBatchCombineParSqrt(b *testing.B) {
 		Ms[i] = suite.PickGT()
 
 	}
-	_, pk := btd.KeyGen(n, t)
+	sks, pk := btd.KeyGen(n, t)
 	ctsR := make([][]be.CT, R)
 	for j := 0; j < R; j++ {
 		cts := make([]be.CT, B)
 		for i := 0; i < B; i++ {
-			ct, err := btd.Enc(pk, i, Ms[j])
+			ct, err := btd.Enc(pk, i, Ms[j], nil)
 			if err != nil {
 				b.Error(err)
 			}
@@ -406,64 +480,121 @@ func BenchmarkBatchCombineParSqrt(b *testing.B) {
 	}
 	factor := 1.0 // Opt-1
 	b.Run(fmt.Sprintf("parallel: B=%d, alpha=%1f*sqrt(B)", 128, factor), func(b *testing.B) {
-		testCombineSqrtParallel(b, R, 128, t, factor, btd, ctsR)
+		testCombineSqrtParallel(b, R, 128, t, factor, btd, sks, ctsR)
 	})
 	b.Run(fmt.Sprintf("parallel: B=%d, alpha=%1f*sqrt(B)", 512, factor), func(b *testing.B) {
-		testCombineSqrtParallel(b, R, 512, t, factor, btd, ctsR)
+		testCombineSqrtParallel(b, R, 512, t, factor, btd, sks, ctsR)
 	})
 	factor = 2.0 // Opt-2
 	b.Run(fmt.Sprintf("parallel: B=%d, alpha=%1f*sqrt(B)", 128, factor), func(b *testing.B) {
-		testCombineSqrtParallel(b, R, 128, t, factor, btd, ctsR)
+		testCombineSqrtParallel(b, R, 128, t, factor, btd, sks, ctsR)
 	})
 	b.Run(fmt.Sprintf("parallel: B=%d, alpha=%1f*sqrt(B)", 512, factor), func(b *testing.B) {
-		testCombineSqrtParallel(b, R, 512, t, factor, btd, ctsR)
+		testCombineSqrtParallel(b, R, 512, t, factor, btd, sks, ctsR)
 	})
 }
 
-func testCombineSqrtParallel(b *testing.B, R, B, t int, factor float64, btd *be.BTD, ctsR [][]be.CT) {
-	SubCtsR := make([][][]be.CT, R)
-	alpha := int(math.Floor(factor * math.Sqrt(float64(B))))
-	subBatchLen := float64(B) / float64(alpha)
-	for r := 0; r < R; r++ {
-		SubCtsR[r] = make([][]be.CT, alpha)
-		for j := 0; j < alpha; j++ {
-			start := int(math.Round(float64(j) * subBatchLen))
-			end := int(math.Round(float64(j+1) * subBatchLen))
-			if j == alpha-1 {
-				end = B
-			}
-			SubCtsR[r][j] = ctsR[r][start:end]
-		}
-	}
-	pdecs := make([][][]*share.PubShare, R)
-	for r := 0; r < R; r++ {
-		pdecs[r] = make([][]*share.PubShare, alpha)
-		for j := 0; j < alpha; j++ {
-			pdecs[r][j] = make([]*share.PubShare, t)
-			for thresh := 0; thresh < t; thresh++ {
-				d, err := btd.BatchDec(SubCtsR[r][j], thresh, false)
-				if err != nil {
-					panic(err)
-				}
-				pdecs[r][j][thresh] = d
-			}
-
-		}
-	}
+func testCombineSqrtParallel(b *testing.B, R, B, t int, factor float64, btd *be.BTD, sks []*share.PriShare, ctsR [][]be.CT) {
+	partsR := partitionR(R, B, factor, ctsR)
+	pdecs := subShares(btd, t, sks, partsR)
 	wg := sync.WaitGroup{}
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		for j := 0; j < alpha; j++ {
+		p, ds := partsR[i%R], pdecs[i%R]
+		for j := range p {
+			shares := make([]*elgamal.DecShare, t)
+			for thresh := range shares {
+				shares[thresh] = ds[thresh][j]
+			}
 			wg.Add(1)
-			go func(ctsSubBatch []be.CT, shares []*share.PubShare) {
+			go func(ctsSubBatch []be.CT, shares []*elgamal.DecShare) {
 				defer wg.Done()
 				_, err := btd.BatchCombine(ctsSubBatch, shares, false)
 				if err != nil {
 					panic(err)
 				}
-			}(SubCtsR[i%R][j], pdecs[i%R][j])
+			}(p[j], shares)
 		}
 	}
 	wg.Wait()
 	b.StopTimer()
 }
+
+// BenchmarkRoles compares the two assignments of the source groups to the roles of the scheme. With the ciphertexts
+// in G1, ciphertexts are small and encryption and partial decryption are fast; with the CRS in G1, the CRS is small
+// and its setup is fast. Every run reports the size of a ciphertext and of the CRS.
+func BenchmarkRoles(b *testing.B) {
+	forEachSuite(b, func(b *testing.B, suite curves.Suite) {
+		for _, roles := range []curves.Roles{curves.CTInG1, curves.CRSInG1} {
+			b.Run(roles.String(), func(b *testing.B) {
+				testBenchmarkRoles(b, curves.WithRoles(suite, roles), 32)
+			})
+		}
+	})
+}
+
+func testBenchmarkRoles(b *testing.B, suite curves.Suite, B int) {
+	btd := be.NewBTD(suite, B)
+	n := 10
+	t := 2
+	sks, pk := btd.KeyGen(n, t)
+	m := suite.PickGT()
+	cts := make([]be.CT, B)
+	for i := range cts {
+		ct, err := btd.Enc(pk, i, m, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		cts[i] = ct
+	}
+	d := make([]*elgamal.DecShare, t)
+	for i := range d {
+		var err error
+		if d[i], err = btd.BatchDec(cts, sks[i], false); err != nil {
+			b.Fatal(err)
+		}
+	}
+	ct, err := cts[0].MarshalBinary()
+	if err != nil {
+		b.Fatal(err)
+	}
+	crs, err := btd.CRS().WriteTo(io.Discard)
+	if err != nil {
+		b.Fatal(err)
+	}
+	sizes := func(b *testing.B) {
+		b.ReportMetric(float64(len(ct)), "ct-bytes")
+		b.ReportMetric(float64(crs), "crs-bytes")
+	}
+
+	b.Run(fmt.Sprintf("setup: B=%d", B), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			be.NewBTD(suite, B)
+		}
+		sizes(b)
+	})
+	b.Run("enc", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := btd.Enc(pk, i%B, m, nil); err != nil {
+				b.Error(err)
+			}
+		}
+		sizes(b)
+	})
+	b.Run(fmt.Sprintf("pdec: B=%d", B), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := btd.BatchDec(cts, sks[0], true); err != nil {
+				b.Error(err)
+			}
+		}
+		sizes(b)
+	})
+	b.Run(fmt.Sprintf("combine: B=%d", B), func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := btd.BatchCombine(cts, d, false); err != nil {
+				b.Error(err)
+			}
+		}
+		sizes(b)
+	})
+}
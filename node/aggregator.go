@@ -0,0 +1,154 @@
+package node
+
+import (
+	"btd/be"
+	"btd/elgamal"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrNotEnoughShares is returned by Collect when fewer than t valid decryption shares could be collected.
+var ErrNotEnoughShares = errors.New("not enough valid decryption shares")
+
+// Aggregator collects the decryption shares of a batch from the nodes of a committee.
+type Aggregator struct {
+	btd     *be.BTD
+	Clients []*Client
+	// Retries is the number of times a failed request to a node is repeated. Requests rejected by the node with a
+	// client error other than 408 or 429 are not repeated, as the node would reject them again.
+	Retries int
+	// Backoff is the pause before the first retry, which doubles with every further retry.
+	Backoff time.Duration
+}
+
+// NewAggregator creates an aggregator of the nodes of clients, which retries every request twice.
+func NewAggregator(btd *be.BTD, clients []*Client) *Aggregator {
+	return &Aggregator{btd: btd, Clients: clients, Retries: 2, Backoff: 100 * time.Millisecond}
+}
+
+// Report describes the outcome of Collect. Nodes are identified by the position of their client in Clients.
+type Report struct {
+	// Shares holds the valid decryption shares in the order they arrived, Nodes the nodes they came from.
+	Shares []*elgamal.DecShare
+	Nodes  []int
+	// Failed maps the nodes whose requests failed, or whose shares were invalid, to the cause.
+	Failed map[int]error
+	// Late lists the nodes that had not answered when Collect returned.
+	Late []int
+}
+
+type result struct {
+	node int
+	d    *elgamal.DecShare
+	err  error
+}
+
+// Collect verifies the batch cts and that all ciphertexts are bound to the associated data ad, requests its
+// decryption share from all nodes at once and verifies every share as it arrives. It returns as soon as t valid shares
+// are present, and the requests to the other nodes are canceled. If ctx is done or all nodes have answered before,
+// Collect returns the report with an error wrapping ErrNotEnoughShares. The shares of the report can be combined with
+// BatchCombine without verification.
+func (a *Aggregator) Collect(ctx context.Context, cts []be.CT, ad []byte) (*Report, error) {
+	if _, err := a.btd.EmptySlots(cts); err != nil {
+		return nil, err
+	}
+	if err := be.CheckAD(cts, ad); err != nil {
+		return nil, err
+	}
+	if err := a.btd.VerifyBatch(cts); err != nil {
+		return nil, err
+	}
+	batch, err := be.MarshalBatch(cts)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	results := make(chan result, len(a.Clients))
+	wg := sync.WaitGroup{}
+	for k, c := range a.Clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d, err := a.request(ctx, c, batch, ad)
+			results <- result{node: k, d: d, err: err}
+		}()
+	}
+	r := &Report{Failed: make(map[int]error)}
+	answered := make([]bool, len(a.Clients))
+	indices := make(map[uint32]bool)
+loop:
+	for len(r.Shares) < a.btd.T && len(r.Shares)+len(r.Failed) < len(a.Clients) {
+		var res result
+		select {
+		case res = <-results:
+		case <-ctx.Done():
+			// Results that arrived by the time ctx expired still count.
+			select {
+			case res = <-results:
+			default:
+				break loop
+			}
+		}
+		answered[res.node] = true
+		switch {
+		case res.err != nil:
+			r.Failed[res.node] = res.err
+		case indices[res.d.I]:
+			r.Failed[res.node] = fmt.Errorf("duplicate decryption share %d", res.d.I)
+		default:
+			if err := a.btd.VerifyShare(cts, res.d); err != nil {
+				r.Failed[res.node] = err
+				continue
+			}
+			indices[res.d.I] = true
+			r.Shares = append(r.Shares, res.d)
+			r.Nodes = append(r.Nodes, res.node)
+		}
+	}
+	// Whether Collect is done or ctx expired, the nodes that have not answered are late; their results are dropped.
+	err = ctx.Err()
+	cancel()
+	wg.Wait()
+	for k := range a.Clients {
+		if !answered[k] {
+			r.Late = append(r.Late, k)
+		}
+	}
+	if len(r.Shares) < a.btd.T {
+		if err != nil {
+			return r, fmt.Errorf("%w: got %d of %d: %w", ErrNotEnoughShares, len(r.Shares), a.btd.T, err)
+		}
+		return r, fmt.Errorf("%w: got %d of %d", ErrNotEnoughShares, len(r.Shares), a.btd.T)
+	}
+	return r, nil
+}
+
+// request requests the decryption share of batch from the node of c, retrying failed requests.
+func (a *Aggregator) request(ctx context.Context, c *Client, batch, ad []byte) (*elgamal.DecShare, error) {
+	backoff := a.Backoff
+	for attempt := 0; ; attempt++ {
+		d, err := c.PDec(ctx, batch, ad)
+		if err == nil || attempt == a.Retries || !retryable(err) {
+			return d, err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// retryable reports whether a request that failed with err may succeed when repeated.
+func retryable(err error) bool {
+	var status *StatusError
+	if !errors.As(err, &status) {
+		return true
+	}
+	return status.Code >= 500 || status.Code == http.StatusRequestTimeout || status.Code == http.StatusTooManyRequests
+}
@@ -0,0 +1,95 @@
+package node
+
+import (
+	"btd/be"
+	"btd/elgamal"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// StatusError is returned by a Client when a node answers with a status other than 200 OK.
+type StatusError struct {
+	Code int
+	Msg  string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("node answered %d %s: %s", e.Code, http.StatusText(e.Code), e.Msg)
+}
+
+// Client requests decryption shares from the node at URL.
+type Client struct {
+	URL  string
+	HTTP *http.Client
+	btd  *be.BTD
+}
+
+// NewClient creates a client of the node at url, e.g., "http://localhost:7000", which decodes the shares of the
+// node with btd.
+func NewClient(btd *be.BTD, url string) *Client {
+	return &Client{URL: strings.TrimSuffix(url, "/"), HTTP: http.DefaultClient, btd: btd}
+}
+
+// PDec requests the decryption share of the batch encoded with be.MarshalBatch, whose ciphertexts must be bound to the
+// associated data ad. The node must answer for the same batch, as checked by its digest. The share is not verified,
+// see be.BTD.VerifyShare.
+func (c *Client) PDec(ctx context.Context, batch, ad []byte) (*elgamal.DecShare, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL+"/v1/pdec", bytes.NewReader(batch))
+	if err != nil {
+		return nil, err
+	}
+	digest := Digest(batch)
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set(DigestHeader, digest)
+	if len(ad) > 0 {
+		req.Header.Set(ADHeader, hex.EncodeToString(ad))
+	}
+	data, header, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if got := header.Get(DigestHeader); got != digest {
+		return nil, fmt.Errorf("node answered for batch %s instead of %s", got, digest)
+	}
+	return c.btd.UnmarshalDecShare(data)
+}
+
+// Health fetches the public parameters of the node.
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL+"/healthz", nil)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	h := &Health{}
+	if err := json.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("decoding health: %w", err)
+	}
+	return h, nil
+}
+
+func (c *Client) do(req *http.Request) ([]byte, http.Header, error) {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	// Shares and health reports are small; the bound protects the client from misbehaving nodes.
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, &StatusError{Code: resp.StatusCode, Msg: strings.TrimSpace(string(data))}
+	}
+	return data, resp.Header, nil
+}
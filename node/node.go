@@ -0,0 +1,173 @@
+// Package node serves the partial decryptions of a committee member over HTTP. A Node holds the key share of one
+// member and answers three endpoints:
+//
+//	POST /v1/pdec   body: a batch encoded with be.MarshalBatch, response: the decryption share of the batch
+//	GET  /healthz   response: the public parameters of the node as JSON
+//	GET  /metrics   response: counters in the Prometheus text format
+//
+// The batch only needs to carry the BTD ciphertexts: the payloads of hybrid ciphertexts stay with the combiner, see
+// be.KEMs. The node verifies the proofs of all ciphertexts before decrypting and answers invalid batches with status
+// 400. The ciphertexts must be bound to the associated data in the ADHeader of the request, or to empty associated
+// data without the header, see be.CheckAD. The SHA-256 digest of the batch is returned in the DigestHeader of the
+// response. A client that sets the DigestHeader on the request only gets a share if the node received the batch it
+// expects.
+package node
+
+import (
+	"btd/be"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.dedis.ch/kyber/v4/share"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DigestHeader carries the hex-encoded SHA-256 digest of the batch, see Digest.
+	DigestHeader = "Btd-Batch-Digest"
+	// ADHeader carries the hex-encoded associated data all ciphertexts of the batch must be bound to.
+	ADHeader = "Btd-Associated-Data"
+	// ContentType is the media type of encoded batches and decryption shares.
+	ContentType = "application/octet-stream"
+	// DefaultMaxBatchBytes is the default bound on the size of a batch accepted by a node.
+	DefaultMaxBatchBytes = 64 << 20
+)
+
+// Digest returns the hex-encoded SHA-256 digest of the encoded batch.
+func Digest(batch []byte) string {
+	h := sha256.Sum256(batch)
+	return hex.EncodeToString(h[:])
+}
+
+// Health describes a node. It is served on /healthz.
+type Health struct {
+	Status string `json:"status"`
+	Suite  string `json:"suite"`
+	Index  uint32 `json:"index"`
+	N      int    `json:"n"`
+	T      int    `json:"t"`
+	B      int    `json:"B"`
+}
+
+// Node is the HTTP handler of a committee member. It is safe for concurrent use.
+type Node struct {
+	btd *be.BTD
+	sk  *share.PriShare
+	mux *http.ServeMux
+	// MaxBatchBytes bounds the size of the batches the node accepts. It must not be changed while the node serves.
+	MaxBatchBytes int64
+
+	requests   atomic.Int64
+	rejected   atomic.Int64
+	failed     atomic.Int64
+	ciphertext atomic.Int64
+	pdecNanos  atomic.Int64
+}
+
+// New creates the node of the committee member with key share sk. The key of btd must be set.
+func New(btd *be.BTD, sk *share.PriShare) (*Node, error) {
+	if btd.PublicKey() == nil {
+		return nil, be.ErrNoKey
+	}
+	if int64(sk.I) >= int64(btd.N) {
+		return nil, fmt.Errorf("key share index %d out of range [0, %d-1]", sk.I, btd.N)
+	}
+	n := &Node{btd: btd, sk: sk, mux: http.NewServeMux(), MaxBatchBytes: DefaultMaxBatchBytes}
+	n.mux.HandleFunc("POST /v1/pdec", n.pdec)
+	n.mux.HandleFunc("GET /healthz", n.health)
+	n.mux.HandleFunc("GET /metrics", n.metrics)
+	return n, nil
+}
+
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mux.ServeHTTP(w, r)
+}
+
+func (n *Node) pdec(w http.ResponseWriter, r *http.Request) {
+	n.requests.Add(1)
+	batch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, n.MaxBatchBytes))
+	if err != nil {
+		n.rejected.Add(1)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+	digest := Digest(batch)
+	if want := r.Header.Get(DigestHeader); want != "" && want != digest {
+		n.rejected.Add(1)
+		http.Error(w, fmt.Sprintf("batch digest %s does not match %s", digest, want), http.StatusBadRequest)
+		return
+	}
+	ad, err := hex.DecodeString(r.Header.Get(ADHeader))
+	if err != nil {
+		n.rejected.Add(1)
+		http.Error(w, fmt.Sprintf("invalid associated data: %v", err), http.StatusBadRequest)
+		return
+	}
+	cts, err := n.btd.UnmarshalBatch(batch)
+	if err == nil {
+		err = be.CheckAD(cts, ad)
+	}
+	if err != nil {
+		n.rejected.Add(1)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	start := time.Now()
+	// The batch is verified by BatchDec, so every error is caused by the batch.
+	d, err := n.btd.BatchDec(cts, n.sk, true)
+	n.pdecNanos.Add(int64(time.Since(start)))
+	if err != nil {
+		n.rejected.Add(1)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := n.btd.MarshalDecShare(d)
+	if err != nil {
+		n.failed.Add(1)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	n.ciphertext.Add(int64(len(cts)))
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set(DigestHeader, digest)
+	w.Write(data)
+}
+
+func (n *Node) health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Health{
+		Status: "ok",
+		Suite:  n.btd.CRS().Suite().Name(),
+		Index:  n.sk.I,
+		N:      n.btd.N,
+		T:      n.btd.T,
+		B:      n.btd.B,
+	})
+}
+
+func (n *Node) metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, m := range []struct {
+		name, help string
+		value      any
+	}{
+		{"btd_node_pdec_requests_total", "Partial decryption requests received.", n.requests.Load()},
+		{"btd_node_pdec_rejected_total", "Partial decryption requests rejected for an invalid batch.", n.rejected.Load()},
+		{"btd_node_pdec_failed_total", "Partial decryption requests failed for an internal error.", n.failed.Load()},
+		{"btd_node_pdec_ciphertexts_total", "Ciphertexts partially decrypted.", n.ciphertext.Load()},
+		{"btd_node_pdec_seconds_total", "Time spent verifying and decrypting batches.",
+			time.Duration(n.pdecNanos.Load()).Seconds()},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %v\n", m.name, m.help, m.name, m.name, m.value)
+	}
+}
@@ -0,0 +1,205 @@
+package node_test
+
+import (
+	"btd/be"
+	"btd/curves"
+	"btd/elgamal"
+	"btd/node"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/pairing/bls12381/kilic"
+	"go.dedis.ch/kyber/v4/share"
+	"go.dedis.ch/kyber/v4/util/random"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// committee starts the nodes of a committee of n members with threshold t on localhost and returns clients of them.
+func committee(t *testing.T, B, n, th int) (*be.BTD, []*node.Client) {
+	btd, nodes := newNodes(t, B, n, th)
+	return btd, serve(t, btd, nodes)
+}
+
+// newNodes creates the nodes of a committee of n members with threshold t.
+func newNodes(t *testing.T, B, n, th int) (*be.BTD, []http.Handler) {
+	btd := be.NewBTD(curves.NewSuite(kilic.NewBLS12381Suite()), B)
+	sks, _ := btd.KeyGen(n, th)
+	nodes := make([]http.Handler, n)
+	for k, sk := range sks {
+		nd, err := node.New(btd, sk)
+		require.NoError(t, err)
+		nodes[k] = nd
+	}
+	return btd, nodes
+}
+
+// serve serves the handlers on localhost and returns clients of them.
+func serve(t *testing.T, btd *be.BTD, handlers []http.Handler) []*node.Client {
+	clients := make([]*node.Client, len(handlers))
+	for k, h := range handlers {
+		srv := httptest.NewServer(h)
+		t.Cleanup(srv.Close)
+		clients[k] = node.NewClient(btd, srv.URL)
+	}
+	return clients
+}
+
+func TestCommittee(t *testing.T) {
+	btd, clients := committee(t, 8, 5, 3)
+	pk := btd.PublicKey()
+	var cts []be.BytesCT
+	for _, i := range []int{6, 0, 3, 5} {
+		ct, err := btd.EncBytes(pk, i, []byte(fmt.Sprintf("message %d", i)), []byte("epoch 1"))
+		require.NoError(t, err)
+		cts = append(cts, ct)
+	}
+	// Only the BTD ciphertexts are sent to the nodes, the payloads stay with the combiner.
+	batch, err := be.MarshalBatch(be.KEMs(cts))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	h, err := clients[4].Health(ctx)
+	require.NoError(t, err)
+	require.Equal(t, node.Health{Status: "ok", Suite: "bls12381-kilic", Index: 4, N: 5, T: 3, B: 8}, *h)
+
+	// A batch is only decrypted for the associated data its ciphertexts are bound to.
+	var status *node.StatusError
+	_, err = clients[0].PDec(ctx, batch, []byte("epoch 2"))
+	require.True(t, errors.As(err, &status))
+	require.Equal(t, http.StatusBadRequest, status.Code)
+	require.Contains(t, status.Msg, be.ErrADMismatch.Error())
+	_, err = clients[0].PDec(ctx, batch, nil)
+	require.ErrorAs(t, err, &status)
+
+	// The shares are requested from the last t nodes, so the shares do not have the indices 0, ..., t-1.
+	var d []*elgamal.DecShare
+	for _, c := range clients[2:] {
+		s, err := c.PDec(ctx, batch, []byte("epoch 1"))
+		require.NoError(t, err)
+		require.NoError(t, btd.VerifyShare(be.KEMs(cts), s))
+		d = append(d, s)
+	}
+	ms, err := btd.BatchCombineBytes(cts, d, true)
+	require.NoError(t, err)
+	for k, ct := range cts {
+		require.Equal(t, fmt.Sprintf("message %d", ct.Index()), string(ms[k]))
+	}
+}
+
+func TestNodeRejects(t *testing.T) {
+	btd, clients := committee(t, 4, 3, 2)
+	c := clients[0]
+	ct, err := btd.Enc(btd.PublicKey(), 1, btd.CRS().Suite().PickGT(), nil)
+	require.NoError(t, err)
+	batch, err := be.MarshalBatch([]be.CT{ct, ct})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	var status *node.StatusError
+	_, err = c.PDec(ctx, batch, nil)
+	require.True(t, errors.As(err, &status))
+	require.Equal(t, http.StatusBadRequest, status.Code)
+	require.Contains(t, status.Msg, be.ErrDuplicateIndex.Error())
+
+	_, err = c.PDec(ctx, batch[:len(batch)-1], nil)
+	require.True(t, errors.As(err, &status))
+	require.Equal(t, http.StatusBadRequest, status.Code)
+
+	// A batch altered in transit is rejected by its digest.
+	batch, err = be.MarshalBatch([]be.CT{ct})
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, c.URL+"/v1/pdec", bytes.NewReader(batch))
+	require.NoError(t, err)
+	req.Header.Set(node.DigestHeader, node.Digest(batch[1:]))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	_, err = c.PDec(ctx, batch, nil)
+	require.NoError(t, err)
+
+	resp, err = http.Get(c.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	metrics, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(metrics), "btd_node_pdec_requests_total 4\n")
+	require.Contains(t, string(metrics), "btd_node_pdec_rejected_total 3\n")
+	require.Contains(t, string(metrics), "btd_node_pdec_ciphertexts_total 1\n")
+}
+
+// slow never answers a request before it is canceled. The server only notices that the client went away once the
+// body has been read.
+var slow = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	<-r.Context().Done()
+})
+
+func TestAggregator(t *testing.T) {
+	btd, nodes := newNodes(t, 8, 6, 3)
+	// Node 1 holds a wrong key share and node 2 fails its first request.
+	wrong, err := node.New(btd, &share.PriShare{I: 1, V: btd.CRS().Suite().G1().Scalar().Pick(random.New())})
+	require.NoError(t, err)
+	nodes[1] = wrong
+	var failed atomic.Bool
+	flaky := nodes[2]
+	nodes[2] = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !failed.Swap(true) {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		flaky.ServeHTTP(w, r)
+	})
+	nodes[0], nodes[5] = slow, slow
+	agg := node.NewAggregator(btd, serve(t, btd, nodes))
+	agg.Backoff = time.Millisecond
+
+	cts, ms := encrypt(t, btd, 1, 2, 7)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	r, err := agg.Collect(ctx, cts, nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []int{2, 3, 4}, r.Nodes)
+	// The share of node 1 may arrive after the third valid share, so node 1 has either failed or is late.
+	require.Subset(t, r.Late, []int{0, 5})
+	require.True(t, failed.Load())
+	res, err := btd.BatchCombine(cts, r.Shares, false)
+	require.NoError(t, err)
+	for k := range ms {
+		require.True(t, ms[k].Equal(res[k]))
+	}
+
+	// With only two nodes answering, the shares are not enough by the deadline.
+	nodes[3] = slow
+	agg.Clients = serve(t, btd, nodes)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	r, err = agg.Collect(ctx, cts, nil)
+	require.ErrorIs(t, err, node.ErrNotEnoughShares)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ElementsMatch(t, []int{2, 4}, r.Nodes)
+	require.Equal(t, []int{0, 3, 5}, r.Late)
+	require.Len(t, r.Failed, 1)
+	require.ErrorContains(t, r.Failed[1], "invalid proof")
+}
+
+func encrypt(t *testing.T, btd *be.BTD, idxs ...int) ([]be.CT, []kyber.Point) {
+	cts := make([]be.CT, len(idxs))
+	ms := make([]kyber.Point, len(idxs))
+	for k, i := range idxs {
+		ms[k] = btd.CRS().Suite().PickGT()
+		ct, err := btd.Enc(btd.PublicKey(), i, ms[k], nil)
+		require.NoError(t, err)
+		cts[k] = ct
+	}
+	return cts, ms
+}
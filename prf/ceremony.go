@@ -0,0 +1,364 @@
+package prf
+
+import (
+	"btd/curves"
+	"btd/transcript"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go.dedis.ch/kyber/v4"
+	"io"
+	"math"
+	"os"
+	"sync"
+)
+
+// Ceremony is a multi-party generation of the CRS. It starts from the CRS of the trivial trapdoor xi = zi = 1 and
+// every participant multiplicatively rerandomizes it with secret scalars ai and bi, i.e., xi <- ai*xi and
+// zi <- bi*zi. As long as one participant erases its scalars, nobody knows the trapdoor of the resulting CRS.
+//
+// The ceremony only keeps the current CRS and, for every contribution, the updated G1xi and g2zi together with
+// proofs of knowledge of the ai and bi. This suffices to verify the whole ceremony: the proofs link every
+// contribution to the previous one and VerifyCRS on the final CRS fixes all remaining elements.
+type Ceremony struct {
+	suite         curves.Suite
+	B             int
+	crs           *CRS
+	Contributions []*Contribution
+}
+
+// Contribution records the rerandomization of the CRS by one participant.
+type Contribution struct {
+	G1xi []kyber.Point
+	g2zi []kyber.Point
+	// xProofs[i] proves knowledge of ai such that G1xi[i] = ai * G1xi'[i], where G1xi' is the previous value.
+	xProofs []dlogProof
+	// zProofs[i] proves knowledge of bi such that g2zi[i] = bi * g2zi'[i], where g2zi' is the previous value.
+	zProofs []dlogProof
+}
+
+// dlogProof is a Schnorr proof of knowledge of the discrete logarithm of a point with respect to a base point.
+type dlogProof struct {
+	R kyber.Point
+	s kyber.Scalar
+}
+
+// NewCeremony starts a ceremony for a CRS of domain size B.
+func NewCeremony(suite curves.Suite, B int) *Ceremony {
+	return &Ceremony{
+		suite: suite,
+		B:     B,
+		crs:   initialCRS(suite, B),
+	}
+}
+
+// initialCRS returns the CRS of the trivial trapdoor xi = zi = 1.
+func initialCRS(suite curves.Suite, B int) *CRS {
+	crs := &CRS{
+		g2zi:   make([]kyber.Point, B),
+		gTzi:   make([]kyber.Point, B),
+		G1xi:   make([]kyber.Point, B),
+		g2zixj: make(map[mkey]kyber.Point, B*(B-1)),
+		B:      B,
+		suite:  suite,
+	}
+	for i := 0; i < B; i++ {
+		crs.G1xi[i] = suite.G1().Point().Base()
+		crs.g2zi[i] = suite.G2().Point().Base()
+		crs.gTzi[i] = suite.GTBase()
+		for j := 0; j < B; j++ {
+			if j != i {
+				crs.g2zixj[mkey{i: i, j: j}] = suite.G2().Point().Base()
+			}
+		}
+	}
+	return crs
+}
+
+// CRS returns the current CRS of the ceremony. It should only be used after Verify succeeded.
+func (c *Ceremony) CRS() *CRS {
+	return c.crs
+}
+
+// Contribute rerandomizes the current CRS with fresh secret scalars, which are erased before returning.
+func (c *Ceremony) Contribute() error {
+	suite, B, old := c.suite, c.B, c.crs
+	a := make([]kyber.Scalar, B)
+	bs := make([]kyber.Scalar, B)
+	for i := 0; i < B; i++ {
+		a[i] = suite.G1().Scalar().Pick(suite.RandomStream())
+		bs[i] = suite.G2().Scalar().Pick(suite.RandomStream())
+	}
+	defer func() {
+		for i := 0; i < B; i++ {
+			a[i].Zero()
+			bs[i].Zero()
+		}
+	}()
+	k := len(c.Contributions)
+	crs := &CRS{
+		g2zi:   make([]kyber.Point, B),
+		gTzi:   make([]kyber.Point, B),
+		G1xi:   make([]kyber.Point, B),
+		g2zixj: make(map[mkey]kyber.Point, B*(B-1)),
+		B:      B,
+		suite:  suite,
+	}
+	contrib := &Contribution{
+		G1xi:    crs.G1xi,
+		g2zi:    crs.g2zi,
+		xProofs: make([]dlogProof, B),
+		zProofs: make([]dlogProof, B),
+	}
+	for i := 0; i < B; i++ {
+		crs.G1xi[i] = suite.G1().Point().Mul(a[i], old.G1xi[i])
+		crs.g2zi[i] = suite.G2().Point().Mul(bs[i], old.g2zi[i])
+		crs.gTzi[i] = suite.GT().Point().Mul(bs[i], old.gTzi[i])
+		var err error
+		if contrib.xProofs[i], err = c.proveDlog(suite.G1(), "G1xi", k, i, a[i], old.G1xi[i], crs.G1xi[i]); err != nil {
+			return err
+		}
+		if contrib.zProofs[i], err = c.proveDlog(suite.G2(), "g2zi", k, i, bs[i], old.g2zi[i], crs.g2zi[i]); err != nil {
+			return err
+		}
+	}
+	// g2^{zi/xj} <- g2^{zi/xj} * (bi/aj), computed row by row in parallel.
+	rows := make([][]kyber.Point, B)
+	wg := sync.WaitGroup{}
+	wg.Add(B)
+	for i := 0; i < B; i++ {
+		go func(i int) {
+			defer wg.Done()
+			rows[i] = make([]kyber.Point, B)
+			for j := 0; j < B; j++ {
+				if j == i {
+					continue
+				}
+				s := suite.G2().Scalar().Div(bs[i], a[j])
+				rows[i][j] = suite.G2().Point().Mul(s, old.g2zixj[mkey{i: i, j: j}])
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < B; i++ {
+		for j := 0; j < B; j++ {
+			if j != i {
+				crs.g2zixj[mkey{i: i, j: j}] = rows[i][j]
+			}
+		}
+	}
+	c.crs = crs
+	c.Contributions = append(c.Contributions, contrib)
+	return nil
+}
+
+// Verify checks the whole ceremony: every contribution must prove knowledge of its rerandomization of the previous
+// one and the final CRS must be consistent with the last contribution.
+func (c *Ceremony) Verify() error {
+	suite, B := c.suite, c.B
+	if len(c.Contributions) == 0 {
+		return errors.New("ceremony has no contributions")
+	}
+	prevX := make([]kyber.Point, B)
+	prevZ := make([]kyber.Point, B)
+	for i := 0; i < B; i++ {
+		prevX[i] = suite.G1().Point().Base()
+		prevZ[i] = suite.G2().Point().Base()
+	}
+	for k, contrib := range c.Contributions {
+		for i := 0; i < B; i++ {
+			if contrib.G1xi[i].Equal(suite.G1().Point().Null()) || contrib.g2zi[i].Equal(suite.G2().Point().Null()) {
+				return fmt.Errorf("contribution %d: degenerate element on index %d", k, i)
+			}
+			if !c.verifyDlog(suite.G1(), "G1xi", k, i, prevX[i], contrib.G1xi[i], contrib.xProofs[i]) {
+				return fmt.Errorf("contribution %d: invalid proof for G1xi on index %d", k, i)
+			}
+			if !c.verifyDlog(suite.G2(), "g2zi", k, i, prevZ[i], contrib.g2zi[i], contrib.zProofs[i]) {
+				return fmt.Errorf("contribution %d: invalid proof for g2zi on index %d", k, i)
+			}
+		}
+		prevX, prevZ = contrib.G1xi, contrib.g2zi
+	}
+	for i := 0; i < B; i++ {
+		if !c.crs.G1xi[i].Equal(prevX[i]) || !c.crs.g2zi[i].Equal(prevZ[i]) {
+			return fmt.Errorf("crs does not match the last contribution on index %d", i)
+		}
+	}
+	return VerifyCRS(c.crs, true)
+}
+
+func (c *Ceremony) proveDlog(
+	g kyber.Group, label string, k, i int, x kyber.Scalar, base, pub kyber.Point,
+) (dlogProof, error) {
+	r := g.Scalar().Pick(c.suite.RandomStream())
+	R := g.Point().Mul(r, base)
+	e, err := c.challenge(g, label, k, i, base, pub, R)
+	if err != nil {
+		return dlogProof{}, err
+	}
+	return dlogProof{
+		R: R,
+		s: g.Scalar().Add(r, g.Scalar().Mul(e, x)),
+	}, nil
+}
+
+func (c *Ceremony) verifyDlog(g kyber.Group, label string, k, i int, base, pub kyber.Point, pi dlogProof) bool {
+	e, err := c.challenge(g, label, k, i, base, pub, pi.R)
+	if err != nil {
+		return false
+	}
+	l := g.Point().Mul(pi.s, base)
+	r := g.Point().Add(pi.R, g.Point().Mul(e, pub))
+	return l.Equal(r)
+}
+
+// challenge computes the challenge for the Schnorr proof of contribution k on index i.
+func (c *Ceremony) challenge(g kyber.Group, label string, k, i int, base, pub, R kyber.Point) (kyber.Scalar, error) {
+	t := transcript.New("btd-ceremony-" + label)
+	t.Append("suite", []byte(c.suite.Name()))
+	t.AppendUint32("B", uint32(c.B))
+	t.AppendUint32("contribution", uint32(k))
+	t.AppendUint32("index", uint32(i))
+	t.AppendMarshaler("base", base)
+	t.AppendMarshaler("pub", pub)
+	t.AppendMarshaler("R", R)
+	return t.Challenge("e", g)
+}
+
+// File format of a ceremony, all integers are big-endian:
+//
+//	magic         "BTDMPC"
+//	version       uint8
+//	suite         uint8 length || suite name
+//	B             uint32
+//	contributions uint32
+//	per contribution and per index i: G1xi, R, s of the G1xi proof, g2zi, R, s of the g2zi proof
+//	crs           the current CRS, as written by CRS.WriteTo
+const (
+	ceremonyMagic   = "BTDMPC"
+	ceremonyVersion = 2
+)
+
+// WriteTo writes the ceremony to w, so that it can be passed on to the next participant.
+func (c *Ceremony) WriteTo(w io.Writer) (int64, error) {
+	name := c.suite.Name()
+	if len(name) > math.MaxUint8 {
+		return 0, fmt.Errorf("suite name too long: %s", name)
+	}
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	cw.Write([]byte(ceremonyMagic))
+	cw.Write([]byte{ceremonyVersion, uint8(len(name))})
+	cw.Write([]byte(name))
+	cw.Write(binary.BigEndian.AppendUint32(nil, uint32(c.B)))
+	cw.Write(binary.BigEndian.AppendUint32(nil, uint32(len(c.Contributions))))
+	for _, contrib := range c.Contributions {
+		for i := 0; i < c.B; i++ {
+			contrib.G1xi[i].MarshalTo(cw)
+			contrib.xProofs[i].R.MarshalTo(cw)
+			contrib.xProofs[i].s.MarshalTo(cw)
+			contrib.g2zi[i].MarshalTo(cw)
+			contrib.zProofs[i].R.MarshalTo(cw)
+			contrib.zProofs[i].s.MarshalTo(cw)
+		}
+	}
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	n, err := c.crs.WriteTo(w)
+	return cw.n + n, err
+}
+
+// ReadCeremony reads a ceremony written by WriteTo. If suite is nil, the ceremony is read in the registered suite
+// named in the file. The ceremony is not verified.
+func ReadCeremony(suite curves.Suite, r io.Reader) (*Ceremony, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(ceremonyMagic)+2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("reading ceremony header: %w", err)
+	}
+	if string(header[:len(ceremonyMagic)]) != ceremonyMagic {
+		return nil, errors.New("not a ceremony file")
+	}
+	if v := header[len(ceremonyMagic)]; v != ceremonyVersion {
+		return nil, fmt.Errorf("unsupported ceremony version %d", v)
+	}
+	name := make([]byte, header[len(ceremonyMagic)+1])
+	if _, err := io.ReadFull(br, name); err != nil {
+		return nil, fmt.Errorf("reading ceremony header: %w", err)
+	}
+	suite, err := resolveSuite(suite, string(name), "ceremony")
+	if err != nil {
+		return nil, err
+	}
+	var sizes [8]byte
+	if _, err := io.ReadFull(br, sizes[:]); err != nil {
+		return nil, fmt.Errorf("reading ceremony header: %w", err)
+	}
+	B := int(binary.BigEndian.Uint32(sizes[:4]))
+	n := int(binary.BigEndian.Uint32(sizes[4:]))
+	if B < 1 || B > maxB {
+		return nil, fmt.Errorf("invalid crs size %d", B)
+	}
+	c := &Ceremony{
+		suite: suite,
+		B:     B,
+	}
+	for k := 0; k < n; k++ {
+		contrib := &Contribution{
+			G1xi:    make([]kyber.Point, B),
+			g2zi:    make([]kyber.Point, B),
+			xProofs: make([]dlogProof, B),
+			zProofs: make([]dlogProof, B),
+		}
+		for i := 0; i < B; i++ {
+			contrib.G1xi[i] = suite.G1().Point()
+			contrib.xProofs[i] = dlogProof{R: suite.G1().Point(), s: suite.G1().Scalar()}
+			contrib.g2zi[i] = suite.G2().Point()
+			contrib.zProofs[i] = dlogProof{R: suite.G2().Point(), s: suite.G2().Scalar()}
+			for _, m := range []kyber.Marshaling{contrib.G1xi[i], contrib.xProofs[i].R, contrib.xProofs[i].s,
+				contrib.g2zi[i], contrib.zProofs[i].R, contrib.zProofs[i].s} {
+				if _, err := m.UnmarshalFrom(br); err != nil {
+					return nil, fmt.Errorf("reading contribution %d on index %d: %w", k, i, err)
+				}
+			}
+		}
+		c.Contributions = append(c.Contributions, contrib)
+	}
+	crs, err := ReadCRS(suite, br)
+	if err != nil {
+		return nil, err
+	}
+	if crs.B != B {
+		return nil, fmt.Errorf("crs size %d does not match ceremony size %d", crs.B, B)
+	}
+	c.crs = crs
+	return c, nil
+}
+
+// Save writes the ceremony to the file at path.
+func (c *Ceremony) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := c.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadCeremony reads a ceremony from the file at path, see ReadCeremony.
+func LoadCeremony(suite curves.Suite, path string) (*Ceremony, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadCeremony(suite, file)
+}
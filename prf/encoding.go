@@ -0,0 +1,237 @@
+package prf
+
+import (
+	"btd/curves"
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"go.dedis.ch/kyber/v4"
+	"io"
+	"math"
+	"os"
+)
+
+// File format of the public CRS, all integers are big-endian:
+//
+//	magic   "BTDCRS"
+//	version uint8
+//	suite   uint8 length || suite name
+//	B       uint32
+//	G1xi    B G1 elements
+//	g2zi    B G2 elements
+//	gTzi    B GT elements
+//	g2zixj  B*(B-1) G2 elements, ordered by i and then by j, skipping j == i
+//
+// Group elements are written in their fixed-size marshalled form. Only public elements are written: the trapdoor
+// (xi, zi) and the diagonal elements g2^{zi/xi} are never part of the file.
+const (
+	crsMagic   = "BTDCRS"
+	crsVersion = 1
+	// maxB is the largest domain size accepted when reading a CRS.
+	maxB = 1 << 12
+	// readChunk is the number of elements read and decoded at once. Memory is only allocated for the elements read so
+	// far, so a header that claims a large B cannot make the reader allocate more than the file provides.
+	readChunk = 1 << 10
+)
+
+// Digest returns a SHA-256 digest that identifies the CRS. It covers the suite, B, G1xi, g2zi and gTzi, but not the
+// off-diagonal elements g2^{zi/xj}: in a CRS that passes VerifyCRS, they are determined by G1xi and g2zi.
+func (f *CRS) Digest() []byte {
+	h := sha256.New()
+	h.Write([]byte(crsMagic))
+	h.Write([]byte{uint8(len(f.suite.Name()))})
+	h.Write([]byte(f.suite.Name()))
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(f.B)))
+	for _, ps := range [][]kyber.Point{f.G1xi, f.g2zi, f.gTzi} {
+		for _, p := range ps {
+			p.MarshalTo(h)
+		}
+	}
+	return h.Sum(nil)
+}
+
+// WriteTo writes the public CRS to w.
+func (f *CRS) WriteTo(w io.Writer) (int64, error) {
+	name := f.suite.Name()
+	if len(name) > math.MaxUint8 {
+		return 0, fmt.Errorf("suite name too long: %s", name)
+	}
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	cw.Write([]byte(crsMagic))
+	cw.Write([]byte{crsVersion, uint8(len(name))})
+	cw.Write([]byte(name))
+	cw.Write(binary.BigEndian.AppendUint32(nil, uint32(f.B)))
+	for _, p := range f.G1xi {
+		p.MarshalTo(cw)
+	}
+	for _, p := range f.g2zi {
+		p.MarshalTo(cw)
+	}
+	for _, p := range f.gTzi {
+		p.MarshalTo(cw)
+	}
+	for i := 0; i < f.B; i++ {
+		for j := 0; j < f.B; j++ {
+			if i == j {
+				continue
+			}
+			p, ok := f.g2zixj[mkey{i: i, j: j}]
+			if !ok {
+				return cw.n, fmt.Errorf("crs element (%d, %d) missing", i, j)
+			}
+			p.MarshalTo(cw)
+		}
+	}
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.(*bufio.Writer).Flush()
+}
+
+// ReadCRS reads a public CRS written by WriteTo. If suite is nil, the CRS is read in the registered suite named in
+// the file. All group elements are checked to be valid elements of their group, but the CRS is not checked to be
+// well-formed.
+func ReadCRS(suite curves.Suite, r io.Reader) (*CRS, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(crsMagic)+2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("reading crs header: %w", err)
+	}
+	if string(header[:len(crsMagic)]) != crsMagic {
+		return nil, errors.New("not a crs file")
+	}
+	if v := header[len(crsMagic)]; v != crsVersion {
+		return nil, fmt.Errorf("unsupported crs version %d", v)
+	}
+	name := make([]byte, header[len(crsMagic)+1])
+	if _, err := io.ReadFull(br, name); err != nil {
+		return nil, fmt.Errorf("reading crs header: %w", err)
+	}
+	suite, err := resolveSuite(suite, string(name), "crs")
+	if err != nil {
+		return nil, err
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(br, size[:]); err != nil {
+		return nil, fmt.Errorf("reading crs header: %w", err)
+	}
+	B := int(binary.BigEndian.Uint32(size[:]))
+	if B < 1 || B > maxB {
+		return nil, fmt.Errorf("invalid crs size %d", B)
+	}
+	f := &CRS{B: B, suite: suite}
+	if f.G1xi, err = readPoints(br, suite.G1(), B, "G1xi"); err != nil {
+		return nil, err
+	}
+	if f.g2zi, err = readPoints(br, suite.G2(), B, "g2zi"); err != nil {
+		return nil, err
+	}
+	if f.gTzi, err = readPoints(br, suite.GT(), B, "gTzi"); err != nil {
+		return nil, err
+	}
+	offDiag, err := readPoints(br, suite.G2(), B*(B-1), "g2zixj")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := br.ReadByte(); err != io.EOF {
+		return nil, errors.New("trailing data after crs")
+	}
+	f.g2zixj = make(map[mkey]kyber.Point, B*(B-1))
+	for i := 0; i < B; i++ {
+		for j := 0; j < B; j++ {
+			if i == j {
+				continue
+			}
+			f.g2zixj[mkey{i: i, j: j}] = offDiag[offDiagIndex(B, i, j)]
+		}
+	}
+	return f, nil
+}
+
+// resolveSuite returns the suite to read an object of kind what tagged with the suite name in. A nil suite selects
+// the registered suite of that name.
+func resolveSuite(suite curves.Suite, name, what string) (curves.Suite, error) {
+	if suite == nil {
+		return curves.Lookup(name)
+	}
+	if name != suite.Name() {
+		return nil, fmt.Errorf("%s for suite %s cannot be read in suite %s", what, name, suite.Name())
+	}
+	return suite, nil
+}
+
+// offDiagIndex returns the position of the element (i, j), i != j, in the row-major order that skips the diagonal.
+func offDiagIndex(B, i, j int) int {
+	if j > i {
+		j--
+	}
+	return i*(B-1) + j
+}
+
+// readPoints reads n fixed-size elements of g from r in chunks of readChunk elements. Decoding validates the
+// elements, which is expensive for large CRS, so it is spread over all CPUs.
+func readPoints(r io.Reader, g kyber.Group, n int, name string) ([]kyber.Point, error) {
+	size := g.PointLen()
+	buf := make([]byte, size*min(n, readChunk))
+	var dst []kyber.Point
+	for len(dst) < n {
+		start := len(dst)
+		chunk := buf[:size*min(n-start, readChunk)]
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+		dst = append(dst, make([]kyber.Point, len(chunk)/size)...)
+		err := parallelIndices(len(chunk)/size, func(k int) error {
+			dst[start+k] = g.Point()
+			if err := dst[start+k].UnmarshalBinary(chunk[k*size : (k+1)*size]); err != nil {
+				return fmt.Errorf("invalid %s element %d: %w", name, start+k, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+// SaveCRS writes the public CRS to the file at path.
+func (f *CRS) SaveCRS(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadCRS reads a public CRS from the file at path, see ReadCRS.
+func LoadCRS(suite curves.Suite, path string) (*CRS, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadCRS(suite, file)
+}
+
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -12,9 +12,18 @@ type mkey struct {
 	j int
 }
 
-type PRF struct {
-	xi     []kyber.Scalar
-	zi     []kyber.Scalar
+// Trapdoor holds the secret scalars xi and zi the CRS is derived from. Anyone knowing the trapdoor can evaluate
+// the PRF on punctured indices, so it must be erased as soon as the public CRS has been computed.
+type Trapdoor struct {
+	xi    []kyber.Scalar
+	zi    []kyber.Scalar
+	B     int
+	suite curves.Suite
+}
+
+// CRS is the public common reference string of the batched PRF. It only contains public elements: neither the
+// trapdoor nor the diagonal elements g2^{zi/xi} are part of it.
+type CRS struct {
 	g2zi   []kyber.Point
 	gTzi   []kyber.Point
 	G1xi   []kyber.Point
@@ -23,32 +32,67 @@ type PRF struct {
 	suite  curves.Suite
 }
 
-func PRFSetup(suite curves.Suite, B int, parallel bool) *PRF {
-	setup := &PRF{
-		xi:     make([]kyber.Scalar, B),
-		zi:     make([]kyber.Scalar, B),
+// PRFSetup samples a fresh trapdoor, derives the public CRS from it and erases the trapdoor.
+func PRFSetup(suite curves.Suite, B int, parallel bool) *CRS {
+	td := SetupTrapdoor(suite, B)
+	defer td.Erase()
+	return td.CRS(parallel)
+}
+
+// SetupTrapdoor samples the secret scalars of a CRS for domain size B.
+func SetupTrapdoor(suite curves.Suite, B int) *Trapdoor {
+	td := &Trapdoor{
+		xi:    make([]kyber.Scalar, B),
+		zi:    make([]kyber.Scalar, B),
+		B:     B,
+		suite: suite,
+	}
+	for i := 0; i < B; i++ {
+		td.xi[i] = suite.G1().Scalar().Pick(suite.RandomStream())
+		td.zi[i] = suite.G2().Scalar().Pick(suite.RandomStream())
+	}
+	return td
+}
+
+// Erase overwrites the secret scalars of the trapdoor. The trapdoor cannot be used afterwards.
+func (td *Trapdoor) Erase() {
+	for i := range td.xi {
+		td.xi[i].Zero()
+		td.zi[i].Zero()
+	}
+	td.xi, td.zi = nil, nil
+}
+
+// CRS computes the public CRS of the trapdoor. The diagonal elements g2^{zi/xi} are not computed, since publishing
+// them would make the scheme insecure.
+func (td *Trapdoor) CRS(parallel bool) *CRS {
+	if td.xi == nil {
+		panic("trapdoor has been erased")
+	}
+	B, suite := td.B, td.suite
+	setup := &CRS{
 		g2zi:   make([]kyber.Point, B),
 		gTzi:   make([]kyber.Point, B),
 		G1xi:   make([]kyber.Point, B),
-		g2zixj: make(map[mkey]kyber.Point),
+		g2zixj: make(map[mkey]kyber.Point, B*(B-1)),
 		B:      B,
 		suite:  suite,
 	}
 	for i := 0; i < B; i++ {
-		setup.xi[i] = suite.G1().Scalar().Pick(suite.RandomStream())
-		setup.zi[i] = suite.G2().Scalar().Pick(suite.RandomStream())
-		setup.G1xi[i] = suite.G1().Point().Mul(setup.xi[i], suite.G1().Point().Base())
-		setup.g2zi[i] = suite.G2().Point().Mul(setup.zi[i], suite.G2().Point().Base())
-		setup.gTzi[i] = suite.GT().Point().Mul(setup.zi[i], suite.GTBase())
+		setup.G1xi[i] = suite.G1().Point().Mul(td.xi[i], suite.G1().Point().Base())
+		setup.g2zi[i] = suite.G2().Point().Mul(td.zi[i], suite.G2().Point().Base())
+		setup.gTzi[i] = suite.GT().Point().Mul(td.zi[i], suite.GTBase())
 	}
 	if !parallel {
 		for i := 0; i < B; i++ {
-			for j := 0; j < B; j++ { // Note that in the REAL setup, one does not compute and publish the values
-				// for j == i!!! Doing so would make it insecure. We just include them here for testing purposes.
+			for j := 0; j < B; j++ {
+				if j == i {
+					continue
+				}
 				setup.g2zixj[mkey{
 					i: i,
 					j: j,
-				}] = suite.G2().Point().Mul(suite.G2().Scalar().Div(setup.zi[i], setup.xi[j]), suite.G2().Point().Base())
+				}] = suite.G2().Point().Mul(suite.G2().Scalar().Div(td.zi[i], td.xi[j]), suite.G2().Point().Base())
 			}
 		}
 		return setup
@@ -71,14 +115,19 @@ func PRFSetup(suite curves.Suite, B int, parallel bool) *PRF {
 			buffer[instance] = make([]struct {
 				mkey
 				kyber.Point
-			}, B*(end-start))
+			}, 0, (B-1)*(end-start))
 			for i := start; i < end; i++ {
 				for j := 0; j < B; j++ {
-					buffer[instance][(i-start)*B+j].mkey = mkey{
-						i: i,
-						j: j,
+					if j == i {
+						continue
 					}
-					buffer[instance][(i-start)*B+j].Point = suite.G2().Point().Mul(suite.G2().Scalar().Div(setup.zi[i], setup.xi[j]), suite.G2().Point().Base())
+					buffer[instance] = append(buffer[instance], struct {
+						mkey
+						kyber.Point
+					}{
+						mkey:  mkey{i: i, j: j},
+						Point: suite.G2().Point().Mul(suite.G2().Scalar().Div(td.zi[i], td.xi[j]), suite.G2().Point().Base()),
+					})
 				}
 			}
 			wg.Done()
@@ -93,11 +142,16 @@ func PRFSetup(suite curves.Suite, B int, parallel bool) *PRF {
 	return setup
 }
 
-func (f *PRF) KeyGen() kyber.Scalar {
+// Suite returns the suite of the CRS.
+func (f *CRS) Suite() curves.Suite {
+	return f.suite
+}
+
+func (f *CRS) KeyGen() kyber.Scalar {
 	return f.suite.G1().Scalar().Pick(f.suite.RandomStream())
 }
 
-func (f *PRF) SumKeys(k []kyber.Scalar) kyber.Scalar {
+func (f *CRS) SumKeys(k []kyber.Scalar) kyber.Scalar {
 	sum := f.suite.G1().Scalar().Zero()
 	for _, ki := range k {
 		sum = sum.Add(sum, ki)
@@ -105,7 +159,7 @@ func (f *PRF) SumKeys(k []kyber.Scalar) kyber.Scalar {
 	return sum
 }
 
-func (f *PRF) Puncture(k kyber.Scalar, i int) (kyber.Point, error) {
+func (f *CRS) Puncture(k kyber.Scalar, i int) (kyber.Point, error) {
 	// Verify that the index is within the domain.
 	if i < 0 || i >= f.B {
 		return nil, fmt.Errorf("puncturing index out of domain. Domain: [0, %d-1], index: %d", f.B, i)
@@ -113,7 +167,8 @@ func (f *PRF) Puncture(k kyber.Scalar, i int) (kyber.Point, error) {
 	return f.suite.G1().Point().Mul(k, f.G1xi[i]), nil
 }
 
-func (f *PRF) Eval(k kyber.Scalar, i int) (kyber.Point, error) {
+// Eval evaluates the PRF with key k on index i. It only uses the public element gTzi, so encryptors can run it.
+func (f *CRS) Eval(k kyber.Scalar, i int) (kyber.Point, error) {
 	// Verify that the index is within the domain.
 	if i < 0 || i >= f.B {
 		return nil, fmt.Errorf("evaluation index out of domain. Domain: [0, %d-1], index: %d", f.B, i)
@@ -121,7 +176,7 @@ func (f *PRF) Eval(k kyber.Scalar, i int) (kyber.Point, error) {
 	return f.suite.GT().Point().Mul(k, f.gTzi[i]), nil
 }
 
-func (f *PRF) PEval(kp kyber.Point, pi, i int) (kyber.Point, error) {
+func (f *CRS) PEval(kp kyber.Point, pi, i int) (kyber.Point, error) {
 	// Verify that the indices are within the domain.
 	if i < 0 || i >= f.B {
 		return nil, fmt.Errorf("punctured evaluation index out of domain. Domain: [0, %d-1], index: %d", f.B, i)
@@ -133,14 +188,42 @@ func (f *PRF) PEval(kp kyber.Point, pi, i int) (kyber.Point, error) {
 	if pi == i {
 		return nil, fmt.Errorf("punctured index cannot be the same as the evaluation index")
 	}
-	crselem := f.g2zixj[mkey{
+	crselem, ok := f.g2zixj[mkey{
 		i: i,
 		j: pi,
 	}]
+	if !ok {
+		return nil, fmt.Errorf("crs element for punctured index %d on index %d missing", pi, i)
+	}
 	return f.suite.Pair(kp, crselem), nil
 }
 
-func (f *PRF) ExpEval(K kyber.Point, i int) (kyber.Point, error) {
+// PEvalMany computes sum_j PEval(kps[j], pis[j], i), the sum of the punctured evaluations on index i of the keys kps
+// punctured at pis. The pairings are computed with one multi-pairing if the suite supports it.
+func (f *CRS) PEvalMany(kps []kyber.Point, pis []int, i int) (kyber.Point, error) {
+	if len(kps) != len(pis) {
+		return nil, fmt.Errorf("%d punctured keys but %d punctured indices", len(kps), len(pis))
+	}
+	if i < 0 || i >= f.B {
+		return nil, fmt.Errorf("punctured evaluation index out of domain. Domain: [0, %d-1], index: %d", f.B, i)
+	}
+	crselems := make([]kyber.Point, len(pis))
+	for j, pi := range pis {
+		if pi < 0 || pi >= f.B {
+			return nil, fmt.Errorf("punctured index out of domain for peval. Domain: [0, %d-1], index: %d", f.B, pi)
+		}
+		if pi == i {
+			return nil, fmt.Errorf("punctured index cannot be the same as the evaluation index")
+		}
+		var ok bool
+		if crselems[j], ok = f.g2zixj[mkey{i: i, j: pi}]; !ok {
+			return nil, fmt.Errorf("crs element for punctured index %d on index %d missing", pi, i)
+		}
+	}
+	return f.suite.MultiPair(kps, crselems), nil
+}
+
+func (f *CRS) ExpEval(K kyber.Point, i int) (kyber.Point, error) {
 	// Verify that the index is within the domain.
 	if i < 0 || i >= f.B {
 		return nil, fmt.Errorf("exponential evaluation index out of domain. Domain: [0, %d-1], index: %d", f.B, i)
@@ -0,0 +1,210 @@
+package prf_test
+
+import (
+	"btd/curves"
+	"btd/prf"
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/pairing/bls12381/kilic"
+	"go.dedis.ch/kyber/v4/pairing/bn256"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCRSPersistence(t *testing.T) {
+	for _, suite := range curves.All() {
+		t.Run(suite.Name(), func(t *testing.T) {
+			testCRSPersistence(t, suite)
+		})
+	}
+}
+
+func testCRSPersistence(t *testing.T, suite curves.Suite) {
+	B := 6
+	crs := prf.PRFSetup(suite, B, false)
+	path := filepath.Join(t.TempDir(), "crs.bin")
+	require.NoError(t, crs.SaveCRS(path))
+	loaded, err := prf.LoadCRS(suite, path)
+	require.NoError(t, err)
+	require.Equal(t, B, loaded.B)
+
+	var a, b bytes.Buffer
+	_, err = crs.WriteTo(&a)
+	require.NoError(t, err)
+	_, err = loaded.WriteTo(&b)
+	require.NoError(t, err)
+	require.Equal(t, a.Bytes(), b.Bytes())
+
+	// Without a suite, the CRS is read in the suite named in the file.
+	named, err := prf.ReadCRS(nil, bytes.NewReader(a.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, suite.Name(), named.Suite().Name())
+	require.Equal(t, crs.Digest(), named.Digest())
+
+	// The loaded CRS evaluates the PRF exactly like the original one.
+	k := crs.KeyGen()
+	for i := 0; i < B; i++ {
+		kp, err := loaded.Puncture(k, i)
+		require.NoError(t, err)
+		for j := 0; j < B; j++ {
+			if j == i {
+				continue
+			}
+			want, err := crs.Eval(k, j)
+			require.NoError(t, err)
+			got, err := loaded.PEval(kp, i, j)
+			require.NoError(t, err)
+			require.True(t, want.Equal(got))
+		}
+	}
+
+	// Truncated files and files for other suites are rejected.
+	_, err = prf.ReadCRS(suite, bytes.NewReader(a.Bytes()[:a.Len()-1]))
+	require.Error(t, err)
+	_, err = prf.ReadCRS(suite, bytes.NewReader(append(a.Bytes(), 0)))
+	require.Error(t, err)
+	other := curves.NewSuite(bn256.NewSuite())
+	if suite.Name() == other.Name() {
+		other = curves.NewSuite(kilic.NewBLS12381Suite())
+	}
+	_, err = prf.ReadCRS(other, bytes.NewReader(a.Bytes()))
+	require.Error(t, err)
+
+	// A header that claims the largest B allocates memory for the elements in the file only.
+	huge := bytes.Clone(a.Bytes()[:len("BTDCRS")+2+len(suite.Name())+4])
+	binary.BigEndian.PutUint32(huge[len(huge)-4:], 1<<12)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err = prf.ReadCRS(suite, bytes.NewReader(huge))
+	runtime.ReadMemStats(&after)
+	require.Error(t, err)
+	require.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(1<<20))
+}
+
+func TestTrapdoor(t *testing.T) {
+	suite := curves.NewSuite(kilic.NewBLS12381Suite())
+	B := 4
+	td := prf.SetupTrapdoor(suite, B)
+	seq := td.CRS(false)
+	par := td.CRS(true)
+	td.Erase()
+	require.Panics(t, func() { td.CRS(false) })
+
+	var a, b bytes.Buffer
+	_, err := seq.WriteTo(&a)
+	require.NoError(t, err)
+	_, err = par.WriteTo(&b)
+	require.NoError(t, err)
+	require.Equal(t, a.Bytes(), b.Bytes())
+
+	k := seq.KeyGen()
+	kp, err := seq.Puncture(k, 1)
+	require.NoError(t, err)
+	// The CRS contains no diagonal elements, so the punctured index cannot be evaluated.
+	_, err = seq.PEval(kp, 1, 1)
+	require.Error(t, err)
+}
+
+func TestCeremony(t *testing.T) {
+	suite := curves.NewSuite(kilic.NewBLS12381Suite())
+	B := 4
+	c := prf.NewCeremony(suite, B)
+	require.Error(t, c.Verify())
+	require.NoError(t, c.Contribute())
+	require.NoError(t, c.Verify())
+
+	// Pass the ceremony on to the next participants through its file format.
+	path := filepath.Join(t.TempDir(), "ceremony.bin")
+	for p := 0; p < 2; p++ {
+		require.NoError(t, c.Save(path))
+		var err error
+		c, err = prf.LoadCeremony(suite, path)
+		require.NoError(t, err)
+		require.NoError(t, c.Verify())
+		require.NoError(t, c.Contribute())
+	}
+	require.NoError(t, c.Verify())
+	require.Len(t, c.Contributions, 3)
+
+	crs := c.CRS()
+	k := crs.KeyGen()
+	kp, err := crs.Puncture(k, 0)
+	require.NoError(t, err)
+	want, err := crs.Eval(k, 2)
+	require.NoError(t, err)
+	got, err := crs.PEval(kp, 0, 2)
+	require.NoError(t, err)
+	require.True(t, want.Equal(got))
+
+	// A ceremony whose CRS is replaced by an independent one is rejected.
+	var buf bytes.Buffer
+	_, err = c.WriteTo(&buf)
+	require.NoError(t, err)
+	var other bytes.Buffer
+	_, err = prf.PRFSetup(suite, B, false).WriteTo(&other)
+	require.NoError(t, err)
+	tampered := append(buf.Bytes()[:buf.Len()-other.Len()], other.Bytes()...)
+	forged, err := prf.ReadCeremony(suite, bytes.NewReader(tampered))
+	require.NoError(t, err)
+	require.Error(t, forged.Verify())
+}
+
+func TestVerifyCRS(t *testing.T) {
+	suite := curves.NewSuite(kilic.NewBLS12381Suite())
+	for _, suite := range []curves.Suite{suite, curves.WithRoles(suite, curves.CRSInG1)} {
+		t.Run(suite.Name(), func(t *testing.T) {
+			testVerifyCRS(t, suite)
+		})
+	}
+}
+
+func testVerifyCRS(t *testing.T, suite curves.Suite) {
+	B := 5
+	crs := prf.PRFSetup(suite, B, false)
+	require.NoError(t, prf.VerifyCRS(crs, false))
+	require.NoError(t, prf.VerifyCRS(crs, true))
+
+	// Replace the element g2^{z0/x1} with g2z0, which is a valid G2 element but breaks the CRS.
+	var buf bytes.Buffer
+	_, err := crs.WriteTo(&buf)
+	require.NoError(t, err)
+	data := buf.Bytes()
+	g1Len, g2Len, gtLen := suite.G1().PointLen(), suite.G2().PointLen(), suite.GT().PointLen()
+	g2ziOff := len("BTDCRS") + 2 + len(suite.Name()) + 4 + B*g1Len
+	offDiagOff := g2ziOff + B*g2Len + B*gtLen
+	copy(data[offDiagOff:offDiagOff+g2Len], data[g2ziOff:g2ziOff+g2Len])
+	bad, err := prf.ReadCRS(suite, bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Error(t, prf.VerifyCRS(bad, false))
+	require.Error(t, prf.VerifyCRS(bad, true))
+}
+
+func TestPEvalMany(t *testing.T) {
+	suite := curves.NewSuite(kilic.NewBLS12381Suite())
+	crs := prf.PRFSetup(suite, 5, false)
+	var kps []kyber.Point
+	var pis []int
+	want := suite.GT().Point().Null()
+	for _, pi := range []int{0, 1, 3, 4} {
+		kp, err := crs.Puncture(crs.KeyGen(), pi)
+		require.NoError(t, err)
+		peval, err := crs.PEval(kp, pi, 2)
+		require.NoError(t, err)
+		want = want.Add(want, peval)
+		kps = append(kps, kp)
+		pis = append(pis, pi)
+	}
+	got, err := crs.PEvalMany(kps, pis, 2)
+	require.NoError(t, err)
+	require.True(t, want.Equal(got))
+
+	_, err = crs.PEvalMany(kps, pis, 1)
+	require.Error(t, err)
+	_, err = crs.PEvalMany(kps, pis[1:], 2)
+	require.Error(t, err)
+	_, err = crs.PEvalMany(kps, pis, 5)
+	require.Error(t, err)
+}
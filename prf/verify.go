@@ -0,0 +1,107 @@
+package prf
+
+import (
+	"btd/curves"
+	"errors"
+	"fmt"
+	"go.dedis.ch/kyber/v4"
+	"runtime"
+	"sync"
+)
+
+// VerifyCRS checks that the CRS is well-formed, i.e., that there is a trapdoor (xi, zi) with all xi, zi != 0 such
+// that G1xi = g1^xi, g2zi = g2^zi, gTzi = gT^zi and g2zixj = g2^{zi/xj} for all i != j. It does so by checking
+// gTzi = e(g1, g2zi) and e(G1xj, g2^{zi/xj}) = gTzi.
+//
+// The deterministic check needs B^2 pairings. If randomized is set, the equations are instead combined with random
+// weights: for every j, sum_i ri * g2^{zi/xj} is computed with a multi-scalar multiplication and checked with a
+// single pairing equation against e(g1, sum_i ri * g2zi), which makes the check cheap enough for B = 512.
+func VerifyCRS(f *CRS, randomized bool) error {
+	suite, B := f.suite, f.B
+	if len(f.G1xi) != B || len(f.g2zi) != B || len(f.gTzi) != B {
+		return errors.New("crs has wrong size")
+	}
+	for i := 0; i < B; i++ {
+		if f.G1xi[i].Equal(suite.G1().Point().Null()) || f.g2zi[i].Equal(suite.G2().Point().Null()) {
+			return fmt.Errorf("degenerate crs element on index %d", i)
+		}
+		for j := 0; j < B; j++ {
+			if _, ok := f.g2zixj[mkey{i: i, j: j}]; j != i && !ok {
+				return fmt.Errorf("crs element (%d, %d) missing", i, j)
+			}
+		}
+	}
+	if !randomized {
+		return verifyCRSFull(f)
+	}
+	return verifyCRSRandomized(f)
+}
+
+func verifyCRSFull(f *CRS) error {
+	suite := f.suite
+	g1 := suite.G1().Point().Base()
+	for i := 0; i < f.B; i++ {
+		if !suite.Pair(g1, f.g2zi[i]).Equal(f.gTzi[i]) {
+			return fmt.Errorf("gTzi inconsistent with g2zi on index %d", i)
+		}
+	}
+	return parallelIndices(f.B, func(j int) error {
+		for i := 0; i < f.B; i++ {
+			if i != j && !suite.Pair(f.G1xi[j], f.g2zixj[mkey{i: i, j: j}]).Equal(f.gTzi[i]) {
+				return fmt.Errorf("crs element (%d, %d) inconsistent with G1xj and gTzi", i, j)
+			}
+		}
+		return nil
+	})
+}
+
+func verifyCRSRandomized(f *CRS) error {
+	suite, B := f.suite, f.B
+	// e(g1, sum_i ri * g2zi) = sum_i ri * gTzi
+	r := curves.RandomWeights(suite.G2(), suite.RandomStream(), B)
+	Z := curves.MultiScalarMul(suite.G2(), r, f.g2zi)
+	if !suite.Pair(suite.G1().Point().Base(), Z).Equal(curves.MultiScalarMul(suite.GT(), r, f.gTzi)) {
+		return errors.New("gTzi inconsistent with g2zi")
+	}
+	// e(G1xj, sum_{i != j} ri * g2^{zi/xj}) = e(g1, sum_{i != j} ri * g2zi) for every j. Given the first check, the
+	// right-hand side equals sum_{i != j} ri * gTzi. Sharing the weights between the checks for different j keeps
+	// the right-hand side cheap and does not affect soundness, as every check on its own is sound.
+	return parallelIndices(B, func(j int) error {
+		rs := make([]kyber.Scalar, 0, B-1)
+		ys := make([]kyber.Point, 0, B-1)
+		for i := 0; i < B; i++ {
+			if i != j {
+				rs = append(rs, r[i])
+				ys = append(ys, f.g2zixj[mkey{i: i, j: j}])
+			}
+		}
+		Y := curves.MultiScalarMul(suite.G2(), rs, ys)
+		Zj := suite.G2().Point().Sub(Z, suite.G2().Point().Mul(r[j], f.g2zi[j]))
+		if !suite.ValidatePairing(f.G1xi[j], Y, suite.G1().Point().Base(), Zj) {
+			return fmt.Errorf("crs elements (., %d) inconsistent with G1xj and gTzi", j)
+		}
+		return nil
+	})
+}
+
+// parallelIndices runs check for all indices in [0, n) spread over all CPUs. Every worker stops at its first failed
+// check, and the errors of all workers are returned joined.
+func parallelIndices(n int, check func(int) error) error {
+	par := min(n, runtime.NumCPU())
+	errs := make([]error, par)
+	wg := sync.WaitGroup{}
+	wg.Add(par)
+	for p := 0; p < par; p++ {
+		go func(p int) {
+			defer wg.Done()
+			for k := p; k < n; k += par {
+				if err := check(k); err != nil {
+					errs[p] = err
+					return
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,79 @@
+// Package transcript implements Fiat-Shamir transcripts for the proofs of the scheme.
+//
+// A transcript absorbs labeled messages into a running SHA-512 state. Every message is framed by a type tag, the
+// length-prefixed label and the length-prefixed data, so different sequences of messages never lead to the same
+// state. A challenge seeds a BLAKE2Xb XOF with the current state and samples a scalar from it by rejection sampling,
+// so challenges are uniform in the scalar field instead of being a biased reduction of a hash.
+package transcript
+
+import (
+	"crypto/sha512"
+	"encoding"
+	"encoding/binary"
+	"go.dedis.ch/kyber/v4"
+	"go.dedis.ch/kyber/v4/xof/blake2xb"
+	"hash"
+)
+
+const (
+	tagDomain    = 1
+	tagMessage   = 2
+	tagChallenge = 3
+)
+
+// Transcript is the Fiat-Shamir transcript of one proof. It must not be shared between goroutines.
+type Transcript struct {
+	h   hash.Hash
+	err error
+}
+
+// New starts a transcript for the protocol domain, which separates the challenges of different proofs.
+func New(domain string) *Transcript {
+	t := &Transcript{h: sha512.New()}
+	t.absorb(tagDomain, domain, nil)
+	return t
+}
+
+func (t *Transcript) absorb(tag byte, label string, data []byte) {
+	var buf []byte
+	buf = append(buf, tag)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(label)))
+	buf = append(buf, label...)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(len(data)))
+	t.h.Write(buf)
+	t.h.Write(data)
+}
+
+// Append absorbs the message data under label.
+func (t *Transcript) Append(label string, data []byte) {
+	t.absorb(tagMessage, label, data)
+}
+
+// AppendUint32 absorbs the integer v under label.
+func (t *Transcript) AppendUint32(label string, v uint32) {
+	t.Append(label, binary.BigEndian.AppendUint32(nil, v))
+}
+
+// AppendMarshaler absorbs the encoding of m, e.g., a point or a scalar, under label. An encoding error is returned
+// by the next Challenge.
+func (t *Transcript) AppendMarshaler(label string, m encoding.BinaryMarshaler) {
+	if t.err != nil {
+		return
+	}
+	b, err := m.MarshalBinary()
+	if err != nil {
+		t.err = err
+		return
+	}
+	t.Append(label, b)
+}
+
+// Challenge returns a uniform scalar of g derived from everything absorbed so far and label. The label is absorbed
+// as well, so later challenges differ from this one.
+func (t *Transcript) Challenge(label string, g kyber.Group) (kyber.Scalar, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	t.absorb(tagChallenge, label, nil)
+	return g.Scalar().Pick(blake2xb.New(t.h.Sum(nil))), nil
+}
@@ -0,0 +1,54 @@
+package transcript_test
+
+import (
+	"btd/transcript"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v4/pairing/bls12381/kilic"
+	"testing"
+)
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalBinary() ([]byte, error) {
+	return nil, errors.New("marshal failed")
+}
+
+func TestTranscript(t *testing.T) {
+	suite := kilic.NewBLS12381Suite()
+	g := suite.G1()
+	P := g.Point().Pick(suite.RandomStream())
+	challenge := func(domain string, msgs ...string) []byte {
+		tr := transcript.New(domain)
+		for i := 0; i+1 < len(msgs); i += 2 {
+			tr.Append(msgs[i], []byte(msgs[i+1]))
+		}
+		tr.AppendMarshaler("P", P)
+		c, err := tr.Challenge("c", g)
+		require.NoError(t, err)
+		b, err := c.MarshalBinary()
+		require.NoError(t, err)
+		return b
+	}
+
+	// Challenges are deterministic.
+	require.Equal(t, challenge("d", "a", "bc"), challenge("d", "a", "bc"))
+	// Messages are framed, so moving bytes between labels and data or between messages changes the challenge.
+	require.NotEqual(t, challenge("d", "a", "bc"), challenge("d", "ab", "c"))
+	require.NotEqual(t, challenge("d", "a", "bc"), challenge("d", "a", "b", "", "c"))
+	require.NotEqual(t, challenge("d", "a", "bc"), challenge("e", "a", "bc"))
+
+	// Successive challenges differ.
+	tr := transcript.New("d")
+	c1, err := tr.Challenge("c", g)
+	require.NoError(t, err)
+	c2, err := tr.Challenge("c", g)
+	require.NoError(t, err)
+	require.False(t, c1.Equal(c2))
+
+	// Encoding errors surface at the challenge.
+	tr = transcript.New("d")
+	tr.AppendMarshaler("x", failingMarshaler{})
+	_, err = tr.Challenge("c", g)
+	require.Error(t, err)
+}